@@ -71,7 +71,8 @@ type Subscription struct {
 		Name           string  `json:"name"`
 		Description    string  `json:"description"`
 		SKU            string  `json:"sku"`
-		Price          float64 `json:"price"`
+		Currency       string  `json:"currency"`
+		UnitAmount     int64   `json:"unit_amount"`
 		ValidityMonths int     `json:"validity_months"`
 	} `json:"pack"`
 	RequestedAt   string `json:"requested_at"`
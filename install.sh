@@ -58,10 +58,10 @@ echo ""
 echo "🎉 Installation completed successfully!"
 echo ""
 echo "To run the application:"
-echo "  ./license-management-api"
+echo "  JWT_SECRET=<your-secret> ./license-management-api"
 echo ""
-echo "Or with Go:"
-echo "  go run main.go"
+echo "Or with Go (local development):"
+echo "  DEV_MODE=true go run main.go"
 echo ""
 echo "The API will be available at: http://localhost:8080"
 echo "Swagger documentation: http://localhost:8080/swagger/index.html"
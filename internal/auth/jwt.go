@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"cursor-ai-backend/internal/config"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the JWT claims structure for frontend access tokens
+type Claims struct {
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager signs and verifies access tokens using the configured secret,
+// issuer, audience and lifetime, and mints opaque refresh tokens
+type TokenManager struct {
+	secret     []byte
+	issuer     string
+	audience   string
+	ttl        time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenManager(cfg *config.Config) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(cfg.JWTSecret),
+		issuer:     cfg.JWTIssuer,
+		audience:   cfg.JWTAudience,
+		ttl:        cfg.JWTTokenTTL,
+		refreshTTL: cfg.RefreshTokenTTL,
+	}
+}
+
+// RefreshTTL returns how long a refresh token stays valid after it is issued
+func (m *TokenManager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// Generate creates a signed access token for the user bound to a session.
+// It returns the token and its expiry time.
+func (m *TokenManager) Generate(user *models.User, sessionID uint) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+	claims := Claims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Audience:  jwt.ClaimStrings{m.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(m.secret)
+	return signed, expiresAt, err
+}
+
+// Parse validates the token signature, issuer, audience and expiry
+func (m *TokenManager) Parse(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(m.audience),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+// NewRefreshToken creates a random refresh token and the hash to store for it
+func NewRefreshToken() (token string, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(bytes)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of an opaque token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,21 +1,125 @@
 package config
 
 import (
+	"errors"
+	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
+// DefaultJWTSecret is the placeholder secret that must not be used outside dev mode
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
 type Config struct {
-	DatabasePath string
-	JWTSecret    string
-	Port         string
+	DatabaseDriver      string
+	DatabasePath        string
+	DatabaseDSN         string
+	AutoMigrate         bool
+	JWTSecret           string
+	JWTIssuer           string
+	JWTAudience         string
+	JWTTokenTTL         time.Duration
+	RefreshTokenTTL     time.Duration
+	SDKLoginKeyTTL      time.Duration
+	DevMode             bool
+	Port                string
+	ExpiryCheckInterval time.Duration
+
+	WebhookDispatchInterval time.Duration
+	WebhookMaxAttempts      int
+
+	// Invoices add InvoiceTaxRate percent tax and are due after InvoiceDueDays
+	InvoiceTaxRate float64
+	InvoiceDueDays int
+
+	// PaymentProvider selects the payment gateway used for checkout; empty
+	// disables online payment. PublicURL is where customers reach this server.
+	PaymentProvider      string
+	PaymentWebhookSecret string
+	PublicURL            string
+	// CheckoutSessionTTL is how long a checkout accepts payment before a new
+	// one can be started
+	CheckoutSessionTTL time.Duration
+
+	// License file signing (Ed25519). LicenseTrustedKeys lists retired public
+	// keys as kid=base64 pairs so previously issued files keep verifying.
+	LicenseSigningKey  string
+	LicenseKeyID       string
+	LicenseTrustedKeys string
 }
 
 func Load() *Config {
 	return &Config{
-		DatabasePath: getEnv("DATABASE_PATH", "./license_management.db"),
-		JWTSecret:    getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
-		Port:         getEnv("PORT", "8080"),
+		DatabaseDriver:          getEnv("DATABASE_DRIVER", "sqlite"),
+		DatabasePath:            getEnv("DATABASE_PATH", "./license_management.db"),
+		DatabaseDSN:             getEnv("DATABASE_DSN", ""),
+		AutoMigrate:             getBoolEnv("AUTO_MIGRATE", true),
+		JWTSecret:               getEnv("JWT_SECRET", DefaultJWTSecret),
+		JWTIssuer:               getEnv("JWT_ISSUER", "license-management-system"),
+		JWTAudience:             getEnv("JWT_AUDIENCE", "license-management-api"),
+		JWTTokenTTL:             getDurationEnv("JWT_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:         getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		SDKLoginKeyTTL:          getDurationEnv("SDK_LOGIN_KEY_TTL", 30*24*time.Hour),
+		DevMode:                 getBoolEnv("DEV_MODE", false),
+		Port:                    getEnv("PORT", "8080"),
+		ExpiryCheckInterval:     getDurationEnv("EXPIRY_CHECK_INTERVAL", time.Minute),
+		WebhookDispatchInterval: getDurationEnv("WEBHOOK_DISPATCH_INTERVAL", 5*time.Second),
+		WebhookMaxAttempts:      getIntEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+		InvoiceTaxRate:          getFloatEnv("INVOICE_TAX_RATE", 0),
+		InvoiceDueDays:          getIntEnv("INVOICE_DUE_DAYS", 14),
+		PaymentProvider:         getEnv("PAYMENT_PROVIDER", ""),
+		PaymentWebhookSecret:    getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		PublicURL:               getEnv("PUBLIC_URL", "http://localhost:"+getEnv("PORT", "8080")),
+		CheckoutSessionTTL:      getDurationEnv("CHECKOUT_SESSION_TTL", time.Hour),
+		LicenseSigningKey:       getEnv("LICENSE_SIGNING_KEY", ""),
+		LicenseKeyID:            getEnv("LICENSE_KEY_ID", "default"),
+		LicenseTrustedKeys:      getEnv("LICENSE_TRUSTED_KEYS", ""),
+	}
+}
+
+// DatabaseSource returns the DSN for the configured driver. SQLite falls back
+// to DatabasePath when no DSN is given.
+func (c *Config) DatabaseSource() string {
+	if c.DatabaseDSN == "" && c.DatabaseDriver == "sqlite" {
+		return c.DatabasePath
+	}
+	return c.DatabaseDSN
+}
+
+// Validate refuses insecure settings unless dev mode is explicitly enabled
+func (c *Config) Validate() error {
+	switch c.DatabaseDriver {
+	case "sqlite":
+	case "postgres":
+		if c.DatabaseDSN == "" {
+			return errors.New("DATABASE_DSN is required when DATABASE_DRIVER=postgres")
+		}
+	default:
+		return errors.New("DATABASE_DRIVER must be sqlite or postgres")
+	}
+	if c.SDKLoginKeyTTL <= 0 {
+		return errors.New("SDK_LOGIN_KEY_TTL must be positive")
+	}
+	if c.CheckoutSessionTTL <= 0 {
+		return errors.New("CHECKOUT_SESSION_TTL must be positive")
+	}
+	if c.InvoiceTaxRate > 100 {
+		return errors.New("INVOICE_TAX_RATE is a percentage and must be between 0 and 100")
+	}
+	switch c.PaymentProvider {
+	case "":
+	case "fake":
+		if !c.DevMode {
+			return errors.New("PAYMENT_PROVIDER=fake takes no real payments and requires DEV_MODE=true")
+		}
+	default:
+		return errors.New("PAYMENT_PROVIDER must be empty or fake")
+	}
+	if c.JWTSecret == DefaultJWTSecret && !c.DevMode {
+		return errors.New("JWT_SECRET is set to the default value; set a real secret or enable DEV_MODE=true")
 	}
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -24,3 +128,55 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s (%q), using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid duration for %s (%q), using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("Invalid integer for %s (%q), using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		log.Printf("Invalid number for %s (%q), using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
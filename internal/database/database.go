@@ -1,18 +1,41 @@
 package database
 
 import (
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
 type DB struct {
 	*gorm.DB
 }
 
-func Initialize(databasePath string) (*DB, error) {
-	db, err := gorm.Open(sqlite.Open(databasePath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// Initialize opens the database for the given driver. For sqlite the DSN is a
+// file path, for postgres it is a connection string or URL.
+func Initialize(driver, dsn string) (*DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverSQLite, "":
+		dialector = sqlite.Open(sqliteDSN(dsn))
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, err
@@ -20,3 +43,37 @@ func Initialize(databasePath string) (*DB, error) {
 
 	return &DB{db}, nil
 }
+
+// sqliteDSN makes concurrent writers wait for the lock instead of failing
+// immediately. Transactions start with BEGIN IMMEDIATE so that a transaction
+// that reads before it writes cannot deadlock against another writer.
+func sqliteDSN(path string) string {
+	for _, option := range []string{"_busy_timeout=5000", "_txlock=immediate"} {
+		name, _, _ := strings.Cut(option, "=")
+		if strings.Contains(path, name) {
+			continue
+		}
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path += separator + option
+	}
+	return path
+}
+
+// SearchCondition builds a case-insensitive substring match over the given
+// columns that works on both SQLite and PostgreSQL, which lacks a portable ILIKE
+func SearchCondition(term string, columns ...string) (string, []interface{}) {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	pattern := "%" + escaper.Replace(strings.ToLower(term)) + "%"
+
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = "LOWER(" + column + `) LIKE ? ESCAPE '\'`
+		args[i] = pattern
+	}
+
+	return strings.Join(conditions, " OR "), args
+}
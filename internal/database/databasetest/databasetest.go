@@ -0,0 +1,116 @@
+// Package databasetest opens migrated databases for tests. SQLite is always
+// available; PostgreSQL tests run against the server in TEST_POSTGRES_DSN and
+// are skipped when it is unset.
+package databasetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+
+	"gorm.io/gorm/logger"
+)
+
+// PostgresDSNEnv names the environment variable holding the PostgreSQL
+// connection string, as a URL or key=value pairs
+const PostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+var sequence atomic.Int64
+
+// Drivers runs test once per driver, as the subtests "sqlite" and "postgres".
+// sqlite opens the SQLite database, normally SQLite or SQLiteFile.
+func Drivers(t *testing.T, sqlite func(testing.TB) *database.DB, test func(t *testing.T, db *database.DB)) {
+	t.Run(database.DriverSQLite, func(t *testing.T) {
+		test(t, sqlite(t))
+	})
+	t.Run(database.DriverPostgres, func(t *testing.T) {
+		test(t, Postgres(t))
+	})
+}
+
+// SQLite opens a migrated in-memory SQLite database that lives until the test ends
+func SQLite(t testing.TB) *database.DB {
+	t.Helper()
+	name := fmt.Sprintf("file:test%d?mode=memory&cache=shared", sequence.Add(1))
+	return open(t, database.DriverSQLite, name)
+}
+
+// SQLiteFile opens a migrated SQLite database in a temporary file. Unlike an
+// in-memory database, it lets concurrent transactions wait for each other.
+func SQLiteFile(t testing.TB) *database.DB {
+	t.Helper()
+	return open(t, database.DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// Postgres opens a fresh schema in the PostgreSQL database at TEST_POSTGRES_DSN,
+// migrates it and drops it when the test ends. The test is skipped when the
+// variable is unset.
+func Postgres(t testing.TB) *database.DB {
+	t.Helper()
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+
+	admin, err := database.Initialize(database.DriverPostgres, dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	admin.Logger = logger.Discard
+
+	schema := fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), sequence.Add(1))
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+		if sqlDB, err := admin.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return open(t, database.DriverPostgres, withSearchPath(dsn, schema))
+}
+
+func open(t testing.TB, driver, dsn string) *database.DB {
+	t.Helper()
+	db, err := database.Initialize(driver, dsn)
+	if err != nil {
+		t.Fatalf("open %s: %v", driver, err)
+	}
+	db.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// withSearchPath makes every connection of dsn use schema
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "search_path=" + schema
+}
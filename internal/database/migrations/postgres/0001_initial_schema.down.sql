@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS seats;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS subscription_packs;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,93 @@
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    email text NOT NULL,
+    password text NOT NULL,
+    role text DEFAULT 'customer',
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS customers (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id),
+    name text NOT NULL,
+    phone text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_user_id ON customers (user_id);
+CREATE INDEX IF NOT EXISTS idx_customers_deleted_at ON customers (deleted_at);
+
+CREATE TABLE IF NOT EXISTS subscription_packs (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    description text,
+    sku text NOT NULL,
+    price decimal(10,2) NOT NULL,
+    validity_months integer NOT NULL CHECK (validity_months >= 1 AND validity_months <= 12),
+    max_seats integer NOT NULL DEFAULT 1,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_packs_sku ON subscription_packs (sku);
+CREATE INDEX IF NOT EXISTS idx_subscription_packs_deleted_at ON subscription_packs (deleted_at);
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id bigserial PRIMARY KEY,
+    customer_id bigint NOT NULL REFERENCES customers (id),
+    pack_id bigint NOT NULL REFERENCES subscription_packs (id),
+    status text DEFAULT 'requested',
+    requested_at timestamptz,
+    approved_at timestamptz,
+    assigned_at timestamptz,
+    expires_at timestamptz,
+    deactivated_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS seats (
+    id bigserial PRIMARY KEY,
+    subscription_id bigint NOT NULL REFERENCES subscriptions (id),
+    fingerprint text NOT NULL,
+    hostname text,
+    last_seen_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_seats_subscription_fingerprint ON seats (subscription_id, fingerprint);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id),
+    refresh_token_hash text NOT NULL,
+    user_agent text,
+    ip_address text,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id),
+    name text NOT NULL,
+    prefix text NOT NULL,
+    key_hash text NOT NULL,
+    scopes text,
+    last_used_at timestamptz,
+    expires_at timestamptz,
+    revoked boolean NOT NULL DEFAULT false,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
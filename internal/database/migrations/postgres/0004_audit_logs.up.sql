@@ -0,0 +1,17 @@
+CREATE TABLE audit_logs (
+    id bigserial PRIMARY KEY,
+    admin_id bigint NOT NULL REFERENCES users (id),
+    admin_email text,
+    method text NOT NULL,
+    route text NOT NULL,
+    path text,
+    resource_id text,
+    changes text,
+    status_code integer,
+    success boolean,
+    ip_address text,
+    created_at timestamptz
+);
+CREATE INDEX idx_audit_logs_admin_id ON audit_logs (admin_id);
+CREATE INDEX idx_audit_logs_resource_id ON audit_logs (resource_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
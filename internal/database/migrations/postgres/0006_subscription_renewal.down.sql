@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP COLUMN renewed_from_id;
+ALTER TABLE subscriptions DROP COLUMN auto_renew;
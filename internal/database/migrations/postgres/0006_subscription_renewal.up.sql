@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions ADD COLUMN auto_renew boolean NOT NULL DEFAULT false;
+ALTER TABLE subscriptions ADD COLUMN renewed_from_id bigint REFERENCES subscriptions (id);
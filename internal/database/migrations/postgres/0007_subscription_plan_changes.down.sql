@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_changed_from_id;
+ALTER TABLE subscriptions DROP COLUMN prorated_credit;
+ALTER TABLE subscriptions DROP COLUMN changed_from_id;
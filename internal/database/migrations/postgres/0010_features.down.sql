@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pack_features;
+DROP TABLE IF EXISTS features;
@@ -0,0 +1,22 @@
+CREATE TABLE features (
+    id bigserial PRIMARY KEY,
+    key text NOT NULL,
+    name text NOT NULL,
+    description text,
+    type text NOT NULL,
+    default_value text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_features_key ON features (key);
+
+CREATE TABLE pack_features (
+    id bigserial PRIMARY KEY,
+    pack_id bigint NOT NULL REFERENCES subscription_packs (id),
+    feature_id bigint NOT NULL REFERENCES features (id),
+    value text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_pack_features_pack_feature ON pack_features (pack_id, feature_id);
+CREATE INDEX idx_pack_features_feature_id ON pack_features (feature_id);
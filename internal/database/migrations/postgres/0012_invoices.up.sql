@@ -0,0 +1,42 @@
+CREATE TABLE invoice_sequences (
+    id bigserial PRIMARY KEY,
+    last_number bigint NOT NULL
+);
+INSERT INTO invoice_sequences (id, last_number) VALUES (1, 0);
+
+-- subscription_id has no foreign key so invoices survive subscription deletes
+CREATE TABLE invoices (
+    id bigserial PRIMARY KEY,
+    number text NOT NULL,
+    customer_id bigint NOT NULL REFERENCES customers (id),
+    subscription_id bigint NOT NULL,
+    status text NOT NULL,
+    billing_reason text NOT NULL,
+    currency text NOT NULL,
+    subtotal bigint NOT NULL,
+    tax_rate decimal(5,2) NOT NULL DEFAULT 0,
+    tax_amount bigint NOT NULL,
+    total bigint NOT NULL,
+    period_start timestamptz,
+    period_end timestamptz,
+    issued_at timestamptz,
+    due_at timestamptz,
+    paid_at timestamptz,
+    voided_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_invoices_number ON invoices (number);
+CREATE INDEX idx_invoices_customer_id ON invoices (customer_id);
+CREATE INDEX idx_invoices_subscription_id ON invoices (subscription_id);
+
+CREATE TABLE invoice_lines (
+    id bigserial PRIMARY KEY,
+    invoice_id bigint NOT NULL REFERENCES invoices (id),
+    description text NOT NULL,
+    quantity bigint NOT NULL DEFAULT 1,
+    unit_amount bigint NOT NULL,
+    amount bigint NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX idx_invoice_lines_invoice_id ON invoice_lines (invoice_id);
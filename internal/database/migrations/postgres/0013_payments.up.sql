@@ -0,0 +1,22 @@
+-- subscription_id and invoice_id have no foreign keys so payment records
+-- survive subscription deletes
+CREATE TABLE payments (
+    id bigserial PRIMARY KEY,
+    provider text NOT NULL,
+    session_id text NOT NULL,
+    checkout_url text,
+    customer_id bigint NOT NULL REFERENCES customers (id),
+    subscription_id bigint NOT NULL,
+    invoice_id bigint,
+    status text NOT NULL,
+    amount bigint NOT NULL,
+    currency text NOT NULL,
+    event_id text,
+    failure_reason text,
+    paid_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_payments_session ON payments (provider, session_id);
+CREATE INDEX idx_payments_customer_id ON payments (customer_id);
+CREATE INDEX idx_payments_subscription_id ON payments (subscription_id);
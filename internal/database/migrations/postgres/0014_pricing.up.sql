@@ -0,0 +1,27 @@
+-- Prices move from decimals to integer minor units (cents). Prices recorded
+-- before packs had a currency are taken to be USD.
+ALTER TABLE subscription_packs ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE subscription_packs ADD COLUMN unit_amount bigint NOT NULL DEFAULT 0;
+UPDATE subscription_packs SET unit_amount = ROUND(price * 100)::bigint;
+ALTER TABLE subscription_packs DROP COLUMN price;
+
+-- Prices in other currencies, and overrides for one region (country code)
+CREATE TABLE pack_prices (
+    id bigserial PRIMARY KEY,
+    pack_id bigint NOT NULL REFERENCES subscription_packs (id),
+    currency text NOT NULL,
+    region text NOT NULL DEFAULT '',
+    unit_amount bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_pack_prices_pack_currency_region ON pack_prices (pack_id, currency, region);
+
+ALTER TABLE customers ADD COLUMN currency text;
+ALTER TABLE customers ADD COLUMN country text;
+
+-- Subscriptions keep the price they were sold at
+ALTER TABLE subscriptions ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE subscriptions ADD COLUMN unit_amount bigint NOT NULL DEFAULT 0;
+UPDATE subscriptions SET unit_amount = (SELECT unit_amount FROM subscription_packs WHERE subscription_packs.id = subscriptions.pack_id);
+ALTER TABLE subscriptions ALTER COLUMN prorated_credit TYPE bigint USING ROUND(prorated_credit * 100)::bigint;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_subscriptions_coupon_id;
+ALTER TABLE subscriptions DROP COLUMN discount;
+ALTER TABLE subscriptions DROP COLUMN coupon_id;
+
+DROP TABLE IF EXISTS coupons;
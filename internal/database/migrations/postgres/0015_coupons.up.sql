@@ -0,0 +1,25 @@
+CREATE TABLE coupons (
+    id bigserial PRIMARY KEY,
+    code text NOT NULL,
+    description text,
+    discount_type text NOT NULL,
+    percent_off integer NOT NULL DEFAULT 0,
+    amount_off bigint NOT NULL DEFAULT 0,
+    currency text,
+    valid_from timestamptz,
+    valid_until timestamptz,
+    max_redemptions integer NOT NULL DEFAULT 0,
+    max_per_customer integer NOT NULL DEFAULT 0,
+    times_redeemed integer NOT NULL DEFAULT 0,
+    pack_skus text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE UNIQUE INDEX idx_coupons_code ON coupons (code);
+CREATE INDEX idx_coupons_deleted_at ON coupons (deleted_at);
+
+-- The coupon redeemed by a subscription request and the discount it gave
+ALTER TABLE subscriptions ADD COLUMN coupon_id bigint REFERENCES coupons (id);
+ALTER TABLE subscriptions ADD COLUMN discount bigint NOT NULL DEFAULT 0;
+CREATE INDEX idx_subscriptions_coupon_id ON subscriptions (coupon_id);
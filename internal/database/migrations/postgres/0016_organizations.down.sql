@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_subscriptions_organization_id;
+ALTER TABLE subscriptions DROP COLUMN organization_id;
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,39 @@
+CREATE TABLE organizations (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    customer_id bigint NOT NULL REFERENCES customers (id),
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE INDEX idx_organizations_customer_id ON organizations (customer_id);
+CREATE INDEX idx_organizations_deleted_at ON organizations (deleted_at);
+
+CREATE TABLE organization_members (
+    id bigserial PRIMARY KEY,
+    organization_id bigint NOT NULL REFERENCES organizations (id),
+    customer_id bigint NOT NULL REFERENCES customers (id),
+    role text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_organization_members_org_customer ON organization_members (organization_id, customer_id);
+CREATE INDEX idx_organization_members_customer_id ON organization_members (customer_id);
+
+CREATE TABLE organization_invitations (
+    id bigserial PRIMARY KEY,
+    organization_id bigint NOT NULL REFERENCES organizations (id),
+    email text NOT NULL,
+    role text NOT NULL,
+    token_hash text NOT NULL,
+    invited_by_id bigint NOT NULL REFERENCES customers (id),
+    expires_at timestamptz NOT NULL,
+    accepted_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX idx_organization_invitations_token_hash ON organization_invitations (token_hash);
+CREATE INDEX idx_organization_invitations_organization_id ON organization_invitations (organization_id);
+
+-- Subscriptions owned by an organization, billed to its owner's customer account
+ALTER TABLE subscriptions ADD COLUMN organization_id bigint REFERENCES organizations (id);
+CREATE INDEX idx_subscriptions_organization_id ON subscriptions (organization_id);
@@ -0,0 +1,6 @@
+-- Only remaining super admins get the admin role back; support and billing
+-- staff and removed users are left with roles that cannot sign in
+UPDATE users SET role = 'admin' WHERE role = 'super_admin' AND deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
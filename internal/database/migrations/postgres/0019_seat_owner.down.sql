@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_seats_customer_id;
+ALTER TABLE seats DROP COLUMN customer_id;
@@ -0,0 +1,6 @@
+-- The customer who activated the machine; existing seats belong to the
+-- subscription's customer
+ALTER TABLE seats ADD COLUMN customer_id bigint REFERENCES customers (id);
+UPDATE seats SET customer_id = subscriptions.customer_id FROM subscriptions WHERE subscriptions.id = seats.subscription_id;
+ALTER TABLE seats ALTER COLUMN customer_id SET NOT NULL;
+CREATE INDEX idx_seats_customer_id ON seats (customer_id);
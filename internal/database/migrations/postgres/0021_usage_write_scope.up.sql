@@ -0,0 +1,11 @@
+-- Keys store their scopes when they are created. Keys that held every scope
+-- before usage:write was added get it too, so full-access keys keep full
+-- access.
+UPDATE api_keys SET scopes = left(scopes, -1) || ',"usage:write"]'
+WHERE scopes LIKE '%"subscription:read"%'
+  AND scopes LIKE '%"subscription:request"%'
+  AND scopes LIKE '%"subscription:deactivate"%'
+  AND scopes LIKE '%"license:read"%'
+  AND scopes LIKE '%"seat:read"%'
+  AND scopes LIKE '%"seat:write"%'
+  AND scopes NOT LIKE '%"usage:write"%';
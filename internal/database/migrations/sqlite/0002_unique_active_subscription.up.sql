@@ -0,0 +1,8 @@
+-- Resolve existing duplicates by keeping the newest active subscription per customer
+UPDATE subscriptions
+SET status = 'inactive', deactivated_at = CURRENT_TIMESTAMP
+WHERE status = 'active'
+  AND id NOT IN (SELECT MAX(id) FROM subscriptions WHERE status = 'active' GROUP BY customer_id);
+
+-- At most one active subscription per customer
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE status = 'active';
@@ -0,0 +1,11 @@
+CREATE TABLE subscription_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    subscription_id integer NOT NULL,
+    from_status text,
+    to_status text NOT NULL,
+    actor_id integer REFERENCES users (id),
+    actor_type text NOT NULL,
+    reason text,
+    created_at datetime
+);
+CREATE INDEX idx_subscription_events_subscription_id ON subscription_events (subscription_id);
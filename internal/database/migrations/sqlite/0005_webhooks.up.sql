@@ -0,0 +1,29 @@
+CREATE TABLE webhook_endpoints (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    url text NOT NULL,
+    description text,
+    secret text NOT NULL,
+    events text,
+    active numeric NOT NULL DEFAULT true,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE webhook_deliveries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    endpoint_id integer NOT NULL REFERENCES webhook_endpoints (id),
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at datetime,
+    last_attempt_at datetime,
+    response_status integer,
+    response_body text,
+    last_error text,
+    delivered_at datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions ADD COLUMN auto_renew numeric NOT NULL DEFAULT false;
+ALTER TABLE subscriptions ADD COLUMN renewed_from_id integer REFERENCES subscriptions (id);
@@ -0,0 +1,3 @@
+ALTER TABLE subscriptions ADD COLUMN changed_from_id integer REFERENCES subscriptions (id);
+ALTER TABLE subscriptions ADD COLUMN prorated_credit decimal(10,2) NOT NULL DEFAULT 0;
+CREATE INDEX idx_subscriptions_changed_from_id ON subscriptions (changed_from_id);
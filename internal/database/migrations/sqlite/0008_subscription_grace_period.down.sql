@@ -0,0 +1,8 @@
+UPDATE subscriptions SET status = 'active' WHERE status IN ('grace', 'suspended');
+
+DROP INDEX IF EXISTS idx_subscriptions_active_customer;
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE status = 'active';
+
+ALTER TABLE subscriptions DROP COLUMN suspended_at;
+ALTER TABLE subscriptions DROP COLUMN grace_ends_at;
+ALTER TABLE subscription_packs DROP COLUMN grace_period_days;
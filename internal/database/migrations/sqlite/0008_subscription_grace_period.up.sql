@@ -0,0 +1,7 @@
+ALTER TABLE subscription_packs ADD COLUMN grace_period_days integer NOT NULL DEFAULT 0;
+ALTER TABLE subscriptions ADD COLUMN grace_ends_at datetime;
+ALTER TABLE subscriptions ADD COLUMN suspended_at datetime;
+
+-- Subscriptions in grace or suspended still count as the customer's current subscription
+DROP INDEX IF EXISTS idx_subscriptions_active_customer;
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE status IN ('active', 'grace', 'suspended');
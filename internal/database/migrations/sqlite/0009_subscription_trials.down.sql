@@ -0,0 +1,9 @@
+UPDATE subscriptions SET status = 'expired' WHERE status = 'trial';
+
+DROP INDEX IF EXISTS idx_subscriptions_active_customer;
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE status IN ('active', 'grace', 'suspended');
+
+DROP INDEX IF EXISTS idx_subscriptions_trial_customer_pack;
+ALTER TABLE subscriptions DROP COLUMN trial;
+ALTER TABLE subscription_packs DROP COLUMN trial_days;
+ALTER TABLE subscription_packs DROP COLUMN trial_enabled;
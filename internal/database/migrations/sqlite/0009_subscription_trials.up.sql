@@ -0,0 +1,10 @@
+ALTER TABLE subscription_packs ADD COLUMN trial_enabled numeric NOT NULL DEFAULT false;
+ALTER TABLE subscription_packs ADD COLUMN trial_days integer NOT NULL DEFAULT 0;
+ALTER TABLE subscriptions ADD COLUMN trial numeric NOT NULL DEFAULT false;
+
+-- One trial per customer and pack
+CREATE UNIQUE INDEX idx_subscriptions_trial_customer_pack ON subscriptions (customer_id, pack_id) WHERE trial = true;
+
+-- A running trial holds the customer's current subscription slot
+DROP INDEX IF EXISTS idx_subscriptions_active_customer;
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE status IN ('active', 'grace', 'suspended', 'trial');
@@ -0,0 +1,22 @@
+CREATE TABLE features (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    key text NOT NULL,
+    name text NOT NULL,
+    description text,
+    type text NOT NULL,
+    default_value text,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_features_key ON features (key);
+
+CREATE TABLE pack_features (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    pack_id integer NOT NULL REFERENCES subscription_packs (id),
+    feature_id integer NOT NULL REFERENCES features (id),
+    value text NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_pack_features_pack_feature ON pack_features (pack_id, feature_id);
+CREATE INDEX idx_pack_features_feature_id ON pack_features (feature_id);
@@ -0,0 +1,27 @@
+-- subscription_id has no foreign key so usage history survives subscription deletes
+CREATE TABLE usage_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    customer_id integer NOT NULL REFERENCES customers (id),
+    subscription_id integer NOT NULL,
+    metric text NOT NULL,
+    quantity integer NOT NULL,
+    idempotency_key text NOT NULL,
+    period_start datetime NOT NULL,
+    created_at datetime
+);
+CREATE UNIQUE INDEX idx_usage_events_idempotency ON usage_events (customer_id, idempotency_key);
+CREATE INDEX idx_usage_events_subscription_id ON usage_events (subscription_id);
+
+CREATE TABLE usage_counters (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    customer_id integer NOT NULL REFERENCES customers (id),
+    subscription_id integer NOT NULL,
+    metric text NOT NULL,
+    period_start datetime NOT NULL,
+    period_end datetime NOT NULL,
+    quantity integer NOT NULL DEFAULT 0,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_usage_counters_period ON usage_counters (subscription_id, metric, period_start);
+CREATE INDEX idx_usage_counters_customer_id ON usage_counters (customer_id);
@@ -0,0 +1,42 @@
+CREATE TABLE invoice_sequences (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    last_number integer NOT NULL
+);
+INSERT INTO invoice_sequences (id, last_number) VALUES (1, 0);
+
+-- subscription_id has no foreign key so invoices survive subscription deletes
+CREATE TABLE invoices (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    number text NOT NULL,
+    customer_id integer NOT NULL REFERENCES customers (id),
+    subscription_id integer NOT NULL,
+    status text NOT NULL,
+    billing_reason text NOT NULL,
+    currency text NOT NULL,
+    subtotal integer NOT NULL,
+    tax_rate decimal(5,2) NOT NULL DEFAULT 0,
+    tax_amount integer NOT NULL,
+    total integer NOT NULL,
+    period_start datetime,
+    period_end datetime,
+    issued_at datetime,
+    due_at datetime,
+    paid_at datetime,
+    voided_at datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_invoices_number ON invoices (number);
+CREATE INDEX idx_invoices_customer_id ON invoices (customer_id);
+CREATE INDEX idx_invoices_subscription_id ON invoices (subscription_id);
+
+CREATE TABLE invoice_lines (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    invoice_id integer NOT NULL REFERENCES invoices (id),
+    description text NOT NULL,
+    quantity integer NOT NULL DEFAULT 1,
+    unit_amount integer NOT NULL,
+    amount integer NOT NULL,
+    created_at datetime
+);
+CREATE INDEX idx_invoice_lines_invoice_id ON invoice_lines (invoice_id);
@@ -0,0 +1,13 @@
+UPDATE subscriptions SET prorated_credit = prorated_credit / 100.0;
+ALTER TABLE subscriptions DROP COLUMN unit_amount;
+ALTER TABLE subscriptions DROP COLUMN currency;
+
+ALTER TABLE customers DROP COLUMN country;
+ALTER TABLE customers DROP COLUMN currency;
+
+DROP TABLE IF EXISTS pack_prices;
+
+ALTER TABLE subscription_packs ADD COLUMN price decimal(10,2) NOT NULL DEFAULT 0;
+UPDATE subscription_packs SET price = unit_amount / 100.0;
+ALTER TABLE subscription_packs DROP COLUMN unit_amount;
+ALTER TABLE subscription_packs DROP COLUMN currency;
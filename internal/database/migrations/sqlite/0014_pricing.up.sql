@@ -0,0 +1,27 @@
+-- Prices move from decimals to integer minor units (cents). Prices recorded
+-- before packs had a currency are taken to be USD.
+ALTER TABLE subscription_packs ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE subscription_packs ADD COLUMN unit_amount integer NOT NULL DEFAULT 0;
+UPDATE subscription_packs SET unit_amount = CAST(ROUND(price * 100) AS integer);
+ALTER TABLE subscription_packs DROP COLUMN price;
+
+-- Prices in other currencies, and overrides for one region (country code)
+CREATE TABLE pack_prices (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    pack_id integer NOT NULL REFERENCES subscription_packs (id),
+    currency text NOT NULL,
+    region text NOT NULL DEFAULT '',
+    unit_amount integer NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX idx_pack_prices_pack_currency_region ON pack_prices (pack_id, currency, region);
+
+ALTER TABLE customers ADD COLUMN currency text;
+ALTER TABLE customers ADD COLUMN country text;
+
+-- Subscriptions keep the price they were sold at
+ALTER TABLE subscriptions ADD COLUMN currency text NOT NULL DEFAULT 'USD';
+ALTER TABLE subscriptions ADD COLUMN unit_amount integer NOT NULL DEFAULT 0;
+UPDATE subscriptions SET unit_amount = (SELECT unit_amount FROM subscription_packs WHERE subscription_packs.id = subscriptions.pack_id);
+UPDATE subscriptions SET prorated_credit = CAST(ROUND(prorated_credit * 100) AS integer);
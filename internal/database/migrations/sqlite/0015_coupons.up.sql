@@ -0,0 +1,25 @@
+CREATE TABLE coupons (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    code text NOT NULL,
+    description text,
+    discount_type text NOT NULL,
+    percent_off integer NOT NULL DEFAULT 0,
+    amount_off integer NOT NULL DEFAULT 0,
+    currency text,
+    valid_from datetime,
+    valid_until datetime,
+    max_redemptions integer NOT NULL DEFAULT 0,
+    max_per_customer integer NOT NULL DEFAULT 0,
+    times_redeemed integer NOT NULL DEFAULT 0,
+    pack_skus text,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime
+);
+CREATE UNIQUE INDEX idx_coupons_code ON coupons (code);
+CREATE INDEX idx_coupons_deleted_at ON coupons (deleted_at);
+
+-- The coupon redeemed by a subscription request and the discount it gave
+ALTER TABLE subscriptions ADD COLUMN coupon_id integer REFERENCES coupons (id);
+ALTER TABLE subscriptions ADD COLUMN discount integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_subscriptions_coupon_id ON subscriptions (coupon_id);
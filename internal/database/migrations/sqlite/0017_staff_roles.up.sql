@@ -0,0 +1,10 @@
+-- The single admin role becomes super_admin. Its sessions are revoked so that
+-- tokens carrying the old role stop working.
+UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE revoked_at IS NULL AND user_id IN (SELECT id FROM users WHERE role = 'admin');
+UPDATE users SET role = 'super_admin' WHERE role = 'admin';
+
+-- Removed staff users are soft deleted; audit logs and subscription events
+-- still refer to them
+ALTER TABLE users ADD COLUMN deleted_at datetime;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_active_organization;
+DROP INDEX IF EXISTS idx_subscriptions_active_customer;
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE status IN ('active', 'grace', 'suspended', 'trial');
@@ -0,0 +1,5 @@
+-- An organization's subscription holds the organization's current subscription
+-- slot rather than that of the customer it is billed to
+DROP INDEX IF EXISTS idx_subscriptions_active_customer;
+CREATE UNIQUE INDEX idx_subscriptions_active_customer ON subscriptions (customer_id) WHERE organization_id IS NULL AND status IN ('active', 'grace', 'suspended', 'trial');
+CREATE UNIQUE INDEX idx_subscriptions_active_organization ON subscriptions (organization_id) WHERE organization_id IS NOT NULL AND status IN ('active', 'grace', 'suspended', 'trial');
@@ -0,0 +1,5 @@
+-- The customer who activated the machine; existing seats belong to the
+-- subscription's customer
+ALTER TABLE seats ADD COLUMN customer_id integer REFERENCES customers (id);
+UPDATE seats SET customer_id = (SELECT customer_id FROM subscriptions WHERE subscriptions.id = seats.subscription_id);
+CREATE INDEX idx_seats_customer_id ON seats (customer_id);
@@ -0,0 +1,3 @@
+-- When the checkout session stops accepting payment. Payments from before
+-- this column expire a checkout session TTL after they were created.
+ALTER TABLE payments ADD COLUMN expires_at datetime;
@@ -0,0 +1,2 @@
+-- The granted scope is kept: the keys held every other scope, and the code
+-- this rolls back to still knows usage:write
@@ -30,7 +30,8 @@ type SubscriptionPackResponse struct {
 	Name           string  `json:"name"`
 	Description    string  `json:"description"`
 	SKU            string  `json:"sku"`
-	Price          float64 `json:"price"`
+	Currency       string  `json:"currency"`
+	UnitAmount     int64   `json:"unit_amount"`
 	ValidityMonths int     `json:"validity_months"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
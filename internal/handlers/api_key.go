@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	*BaseHandler
+}
+
+func NewAPIKeyHandler(db *database.DB) *APIKeyHandler {
+	return &APIKeyHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// CreateAPIKeyRequest represents the API key creation request
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreateAPIKeyResponse includes the plaintext key, which is only returned once
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
+
+// ListAPIKeys handles listing the current user's API keys
+// @Summary List API keys
+// @Description List the current customer's API keys. Only the key prefix is returned.
+// @Tags Customer API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/customer/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var keys []models.APIKey
+	err = h.db.Where("user_id = ?", user.ID).Order("created_at DESC").Find(&keys).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve API keys")
+		return
+	}
+
+	h.SuccessResponse(c, keys, "API keys retrieved successfully")
+}
+
+// CreateAPIKey handles creating a new API key for the current user
+// @Summary Create API key
+// @Description Create a named API key. The full key is only returned in this response.
+// @Tags Customer API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "API key details"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/customer/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if req.Name == models.SDKLoginAPIKeyName {
+		h.ErrorResponse(c, http.StatusBadRequest, "This name is reserved for keys issued by SDK login")
+		return
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = models.AllAPIKeyScopes
+	}
+	for _, scope := range scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			h.ErrorResponse(c, http.StatusBadRequest, "Unknown scope: "+scope)
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		expiry := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &expiry
+	}
+
+	key, plaintext, err := models.NewAPIKey(user.ID, req.Name, scopes, expiresAt)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
+
+	if err := h.db.Create(key).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create API key")
+		return
+	}
+
+	h.SuccessResponse(c, CreateAPIKeyResponse{
+		Key:    plaintext,
+		APIKey: key,
+	}, "API key created successfully. Store it now, it will not be shown again")
+}
+
+// RotateAPIKey handles replacing an API key with a new one
+// @Summary Rotate API key
+// @Description Revoke an API key and issue a replacement with the same name and scopes that expires when the old key would have
+// @Tags Customer API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	key, ok := h.findOwnKey(c)
+	if !ok {
+		return
+	}
+
+	if !key.IsUsable() {
+		h.ErrorResponse(c, http.StatusBadRequest, "Revoked or expired API keys cannot be rotated")
+		return
+	}
+
+	// The replacement keeps the remaining lifetime of the old key
+	replacement, plaintext, err := models.NewAPIKey(key.UserID, key.Name, key.Scopes, key.ExpiresAt)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := revokeAPIKey(tx, key); err != nil {
+			return err
+		}
+		return tx.Create(replacement).Error
+	})
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to rotate API key")
+		return
+	}
+
+	h.SuccessResponse(c, CreateAPIKeyResponse{
+		Key:    plaintext,
+		APIKey: replacement,
+	}, "API key rotated successfully. Store it now, it will not be shown again")
+}
+
+// RevokeAPIKey handles revoking an API key
+// @Summary Revoke API key
+// @Description Revoke an API key so it can no longer be used
+// @Tags Customer API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	key, ok := h.findOwnKey(c)
+	if !ok {
+		return
+	}
+
+	if !key.Revoked {
+		if err := revokeAPIKey(h.db.DB, key); err != nil {
+			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke API key")
+			return
+		}
+	}
+
+	h.SuccessResponse(c, key, "API key revoked successfully")
+}
+
+// findOwnKey loads the API key from the path that belongs to the current user,
+// writing an error response and returning false when it does not exist
+func (h *APIKeyHandler) findOwnKey(c *gin.Context) (*models.APIKey, bool) {
+	user, err := h.GetCurrentUser(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "User not found")
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID")
+		return nil, false
+	}
+
+	var key models.APIKey
+	err = h.db.Where("id = ? AND user_id = ?", id, user.ID).First(&key).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "API key not found")
+		return nil, false
+	}
+
+	return &key, true
+}
+
+func revokeAPIKey(db *gorm.DB, key *models.APIKey) error {
+	now := time.Now()
+	key.Revoked = true
+	key.RevokedAt = &now
+	return db.Model(key).Updates(map[string]interface{}{
+		"revoked":    true,
+		"revoked_at": now,
+	}).Error
+}
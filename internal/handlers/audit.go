@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// auditExportBatchSize is how many entries are read per query while exporting
+const auditExportBatchSize = 500
+
+type AuditHandler struct {
+	*BaseHandler
+}
+
+func NewAuditHandler(db *database.DB) *AuditHandler {
+	return &AuditHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// ListAuditLogs handles listing admin audit entries (admin only)
+// @Summary List audit log
+// @Description Get paginated admin audit entries, newest first
+// @Tags Admin Audit
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param admin_id query int false "Filter by admin user ID"
+// @Param method query string false "Filter by HTTP method"
+// @Param route query string false "Filter by route, e.g. /api/v1/admin/packs/:id"
+// @Param resource_id query string false "Filter by target resource ID"
+// @Param success query bool false "Filter by result"
+// @Param from query string false "Only entries at or after this time (RFC 3339)"
+// @Param to query string false "Only entries before this time (RFC 3339)"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/audit [get]
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query, err := h.filteredQuery(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var entries []models.AuditLog
+	err = query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve audit log")
+		return
+	}
+
+	h.PaginatedResponse(c, entries, total, page, limit)
+}
+
+// ExportAuditLogs handles exporting admin audit entries as NDJSON (admin only)
+// @Summary Export audit log
+// @Description Stream all matching audit entries as newline-delimited JSON, oldest first. Accepts the same filters as the list endpoint.
+// @Tags Admin Audit
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param admin_id query int false "Filter by admin user ID"
+// @Param method query string false "Filter by HTTP method"
+// @Param route query string false "Filter by route"
+// @Param resource_id query string false "Filter by target resource ID"
+// @Param success query bool false "Filter by result"
+// @Param from query string false "Only entries at or after this time (RFC 3339)"
+// @Param to query string false "Only entries before this time (RFC 3339)"
+// @Success 200 {string} string "One JSON audit entry per line"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/audit/export [get]
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	query, err := h.filteredQuery(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.ndjson", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	var batch []models.AuditLog
+	err = query.Order("id ASC").FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := encoder.Encode(&batch[i]); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	}).Error
+	if err != nil {
+		// Headers are already sent, so the truncated stream is all we can signal
+		c.Error(err)
+	}
+}
+
+// filteredQuery builds the audit log query from the shared filter parameters
+func (h *AuditHandler) filteredQuery(c *gin.Context) (*gorm.DB, error) {
+	query := h.db.Model(&models.AuditLog{})
+
+	if adminID := c.Query("admin_id"); adminID != "" {
+		id, err := strconv.ParseUint(adminID, 10, 32)
+		if err != nil {
+			return nil, errors.New("Invalid admin_id")
+		}
+		query = query.Where("admin_id = ?", id)
+	}
+	if method := c.Query("method"); method != "" {
+		query = query.Where("method = ?", strings.ToUpper(method))
+	}
+	if route := c.Query("route"); route != "" {
+		query = query.Where("route = ?", route)
+	}
+	if resourceID := c.Query("resource_id"); resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+	if success := c.Query("success"); success != "" {
+		ok, err := strconv.ParseBool(success)
+		if err != nil {
+			return nil, errors.New("Invalid success filter")
+		}
+		query = query.Where("success = ?", ok)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, errors.New("Invalid from time, expected RFC 3339")
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, errors.New("Invalid to time, expected RFC 3339")
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	return query, nil
+}
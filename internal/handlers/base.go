@@ -5,6 +5,7 @@ import (
 
 	"cursor-ai-backend/internal/database"
 	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -47,6 +48,17 @@ func (h *BaseHandler) GetCurrentCustomer(c *gin.Context) (*models.Customer, erro
 	return user.Customer, nil
 }
 
+// CurrentActor describes the authenticated user as the actor of a subscription change
+func (h *BaseHandler) CurrentActor(c *gin.Context, actorType models.ActorType) services.Actor {
+	actor := services.Actor{Type: actorType}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(uint); ok {
+			actor.UserID = &id
+		}
+	}
+	return actor
+}
+
 // SuccessResponse creates a standardized success response
 func (h *BaseHandler) SuccessResponse(c *gin.Context, data interface{}, message string) {
 	response := gin.H{
@@ -81,9 +93,37 @@ func (h *BaseHandler) PaginatedResponse(c *gin.Context, data interface{}, total
 	c.JSON(200, response)
 }
 
-// SubscriptionRequest represents a subscription request
+// SubscriptionRequest represents a subscription request, optionally with a
+// coupon code. Coupons do not apply to trials.
 type SubscriptionRequest struct {
-	PackSKU string `json:"pack_sku" binding:"required"`
+	PackSKU    string `json:"pack_sku" binding:"required"`
+	CouponCode string `json:"coupon_code"`
+}
+
+// ChangePlanRequest represents a request to move a subscription to another pack
+type ChangePlanRequest struct {
+	PackSKU   string `json:"pack_sku" binding:"required"`
+	Effective string `json:"effective" binding:"omitempty,oneof=immediate end_of_term"`
+}
+
+// AutoRenewRequest turns automatic renewal on or off
+type AutoRenewRequest struct {
+	AutoRenew *bool `json:"auto_renew" binding:"required"`
+}
+
+// TransitionRequest carries an optional reason for a subscription status change
+type TransitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// bindTransitionRequest reads an optional TransitionRequest body
+func bindTransitionRequest(c *gin.Context) (TransitionRequest, error) {
+	var req TransitionRequest
+	if c.Request.ContentLength == 0 {
+		return req, nil
+	}
+	err := c.ShouldBindJSON(&req)
+	return req, err
 }
 
 // PaginatedResponse represents a paginated API response
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CouponHandler struct {
+	*BaseHandler
+}
+
+func NewCouponHandler(db *database.DB) *CouponHandler {
+	return &CouponHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// CreateCouponRequest represents a new coupon. Percent coupons set
+// percent_off; fixed coupons set amount_off in minor units of currency.
+type CreateCouponRequest struct {
+	Code           string     `json:"code" binding:"required"`
+	Description    string     `json:"description"`
+	DiscountType   string     `json:"discount_type" binding:"required,oneof=percent fixed"`
+	PercentOff     int        `json:"percent_off" binding:"min=0,max=100"`
+	AmountOff      int64      `json:"amount_off" binding:"min=0"`
+	Currency       string     `json:"currency"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	MaxRedemptions int        `json:"max_redemptions" binding:"min=0"`
+	MaxPerCustomer int        `json:"max_per_customer" binding:"min=0"`
+	PackSKUs       []string   `json:"pack_skus"`
+}
+
+// UpdateCouponRequest represents a coupon update. The code and discount
+// cannot change; create a new coupon instead.
+type UpdateCouponRequest struct {
+	Description    string     `json:"description"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	MaxRedemptions *int       `json:"max_redemptions" binding:"omitempty,min=0"`
+	MaxPerCustomer *int       `json:"max_per_customer" binding:"omitempty,min=0"`
+	PackSKUs       *[]string  `json:"pack_skus"`
+}
+
+// ListCoupons handles listing coupons (admin only)
+// @Summary List coupons
+// @Description Get paginated coupons, newest first
+// @Tags Admin Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Search by code or description"
+// @Success 200 {object} PaginatedResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/coupons [get]
+func (h *CouponHandler) ListCoupons(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Coupon{})
+	if search := c.Query("search"); search != "" {
+		condition, args := database.SearchCondition(search, "code", "description")
+		query = query.Where(condition, args...)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var coupons []models.Coupon
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&coupons).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve coupons")
+		return
+	}
+
+	h.PaginatedResponse(c, coupons, total, page, limit)
+}
+
+// CreateCoupon handles creating a coupon (admin only)
+// @Summary Create coupon
+// @Description Create a percent or fixed discount code, optionally limited to a validity window, a number of redemptions in total and per customer, and specific pack SKUs. Codes are case-insensitive.
+// @Tags Admin Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateCouponRequest true "Coupon"
+// @Success 200 {object} models.Coupon
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/admin/coupons [post]
+func (h *CouponHandler) CreateCoupon(c *gin.Context) {
+	var req CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	coupon := models.Coupon{
+		Code:           models.NormalizeCouponCode(req.Code),
+		Description:    req.Description,
+		DiscountType:   req.DiscountType,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		MaxRedemptions: req.MaxRedemptions,
+		MaxPerCustomer: req.MaxPerCustomer,
+		PackSKUs:       req.PackSKUs,
+	}
+	if !models.IsValidCouponCode(coupon.Code) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Code must be 3-32 letters, digits, '_' or '-'")
+		return
+	}
+
+	switch coupon.DiscountType {
+	case models.DiscountPercent:
+		if req.PercentOff < 1 {
+			h.ErrorResponse(c, http.StatusBadRequest, "Percent coupons need a percent_off between 1 and 100")
+			return
+		}
+		coupon.PercentOff = req.PercentOff
+	case models.DiscountFixed:
+		coupon.Currency = strings.ToUpper(req.Currency)
+		if req.AmountOff < 1 || !models.IsValidCurrency(coupon.Currency) {
+			h.ErrorResponse(c, http.StatusBadRequest, "Fixed coupons need a positive amount_off and an ISO 4217 currency")
+			return
+		}
+		coupon.AmountOff = req.AmountOff
+	}
+
+	if !h.validateCoupon(c, &coupon) {
+		return
+	}
+
+	err := h.db.Create(&coupon).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		h.ErrorResponse(c, http.StatusConflict, "Coupon code already exists")
+		return
+	}
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create coupon")
+		return
+	}
+	middleware.SetAuditResourceID(c, coupon.ID)
+
+	h.SuccessResponse(c, coupon, "Coupon created successfully")
+}
+
+// GetCoupon handles getting a coupon (admin only)
+// @Summary Get coupon
+// @Description Get a coupon by ID, including how often it has been redeemed
+// @Tags Admin Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} models.Coupon
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/coupons/{id} [get]
+func (h *CouponHandler) GetCoupon(c *gin.Context) {
+	coupon, ok := h.findCoupon(c)
+	if !ok {
+		return
+	}
+
+	h.SuccessResponse(c, coupon, "")
+}
+
+// UpdateCoupon handles updating a coupon (admin only)
+// @Summary Update coupon
+// @Description Change a coupon's description, validity window, redemption limits or pack SKUs. Send an empty pack_skus list to allow every pack.
+// @Tags Admin Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Param request body UpdateCouponRequest true "Fields to update"
+// @Success 200 {object} models.Coupon
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/coupons/{id} [put]
+func (h *CouponHandler) UpdateCoupon(c *gin.Context) {
+	var req UpdateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	coupon, ok := h.findCoupon(c)
+	if !ok {
+		return
+	}
+	middleware.SetAuditSnapshot(c, *coupon)
+
+	if req.Description != "" {
+		coupon.Description = req.Description
+	}
+	if req.ValidFrom != nil {
+		coupon.ValidFrom = req.ValidFrom
+	}
+	if req.ValidUntil != nil {
+		coupon.ValidUntil = req.ValidUntil
+	}
+	if req.MaxRedemptions != nil {
+		coupon.MaxRedemptions = *req.MaxRedemptions
+	}
+	if req.MaxPerCustomer != nil {
+		coupon.MaxPerCustomer = *req.MaxPerCustomer
+	}
+	if req.PackSKUs != nil {
+		coupon.PackSKUs = *req.PackSKUs
+	}
+
+	if !h.validateCoupon(c, coupon) {
+		return
+	}
+
+	// Redemptions are counted concurrently, so the count is never written back
+	if err := h.db.Omit("TimesRedeemed").Save(coupon).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update coupon")
+		return
+	}
+
+	h.SuccessResponse(c, coupon, "Coupon updated successfully")
+}
+
+// DeleteCoupon handles deleting a coupon (admin only)
+// @Summary Delete coupon
+// @Description Soft delete a coupon so it can no longer be redeemed. Subscriptions that redeemed it keep their discount.
+// @Tags Admin Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/coupons/{id} [delete]
+func (h *CouponHandler) DeleteCoupon(c *gin.Context) {
+	coupon, ok := h.findCoupon(c)
+	if !ok {
+		return
+	}
+	middleware.SetAuditSnapshot(c, *coupon)
+
+	if err := h.db.Delete(coupon).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete coupon")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Coupon deleted successfully"}, "")
+}
+
+// ListCouponRedemptions handles listing the subscriptions that redeemed a coupon (admin only)
+// @Summary List coupon redemptions
+// @Description Get the paginated subscriptions that redeemed a coupon, newest first, with the discount each received
+// @Tags Admin Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} PaginatedResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/coupons/{id}/redemptions [get]
+func (h *CouponHandler) ListCouponRedemptions(c *gin.Context) {
+	coupon, ok := h.findCoupon(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Subscription{}).Where("coupon_id = ?", coupon.ID)
+
+	var total int64
+	query.Count(&total)
+
+	var subscriptions []models.Subscription
+	err := query.Preload("Customer.User").Preload("Pack").Order("id DESC").Offset(offset).Limit(limit).Find(&subscriptions).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve coupon redemptions")
+		return
+	}
+
+	h.PaginatedResponse(c, subscriptions, total, page, limit)
+}
+
+// validateCoupon checks the validity window and that every pack SKU the
+// coupon is restricted to exists
+func (h *CouponHandler) validateCoupon(c *gin.Context, coupon *models.Coupon) bool {
+	if coupon.ValidFrom != nil && coupon.ValidUntil != nil && !coupon.ValidUntil.After(*coupon.ValidFrom) {
+		h.ErrorResponse(c, http.StatusBadRequest, "valid_until must be after valid_from")
+		return false
+	}
+
+	for _, sku := range coupon.PackSKUs {
+		var count int64
+		if err := h.db.Model(&models.SubscriptionPack{}).Where("sku = ?", sku).Count(&count).Error; err != nil {
+			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to check subscription packs")
+			return false
+		}
+		if count == 0 {
+			h.ErrorResponse(c, http.StatusBadRequest, "Unknown subscription pack SKU: "+sku)
+			return false
+		}
+	}
+	return true
+}
+
+func (h *CouponHandler) findCoupon(c *gin.Context) (*models.Coupon, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid coupon ID")
+		return nil, false
+	}
+
+	var coupon models.Coupon
+	if err := h.db.First(&coupon, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Coupon not found")
+		return nil, false
+	}
+	return &coupon, true
+}
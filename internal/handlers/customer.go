@@ -3,8 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
 	"cursor-ai-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -26,12 +28,37 @@ type CreateCustomerRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
 	Phone    string `json:"phone"`
+	Currency string `json:"currency"`
+	Country  string `json:"country"`
 }
 
-// UpdateCustomerRequest represents the customer update request
+// UpdateCustomerRequest represents the customer update request. An empty
+// currency or country clears the preference.
 type UpdateCustomerRequest struct {
-	Name  string `json:"name"`
-	Phone string `json:"phone"`
+	Name     string  `json:"name"`
+	Phone    string  `json:"phone"`
+	Currency *string `json:"currency"`
+	Country  *string `json:"country"`
+}
+
+// setPricingPreferences sets the customer's preferred currency and country,
+// when given, and reports whether they are valid codes
+func setPricingPreferences(customer *models.Customer, currency, country *string) bool {
+	if currency != nil {
+		code := strings.ToUpper(strings.TrimSpace(*currency))
+		if code != "" && !models.IsValidCurrency(code) {
+			return false
+		}
+		customer.Currency = code
+	}
+	if country != nil {
+		code := strings.ToUpper(strings.TrimSpace(*country))
+		if code != "" && !models.IsValidRegion(code) {
+			return false
+		}
+		customer.Country = code
+	}
+	return true
 }
 
 // ListCustomers handles listing all customers (admin only)
@@ -68,8 +95,8 @@ func (h *CustomerHandler) ListCustomers(c *gin.Context) {
 
 	// Apply search filter
 	if search != "" {
-		query = query.Joins("JOIN users ON customers.user_id = users.id").
-			Where("customers.name ILIKE ? OR users.email ILIKE ?", "%"+search+"%", "%"+search+"%")
+		condition, args := database.SearchCondition(search, "customers.name", "users.email")
+		query = query.Joins("JOIN users ON customers.user_id = users.id").Where(condition, args...)
 	}
 
 	// Get total count
@@ -108,9 +135,15 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
+	var preferences models.Customer
+	if !setPricingPreferences(&preferences, &req.Currency, &req.Country) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code and country an ISO 3166 alpha-2 code")
+		return
+	}
+
 	// Check if user already exists
 	var existingUser models.User
-	err := h.db.Where("email = ?", req.Email).First(&existingUser).Error
+	err := h.db.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error
 	if err == nil {
 		h.ErrorResponse(c, http.StatusConflict, "Email already registered")
 		return
@@ -120,7 +153,7 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 	user := &models.User{
 		Email:    req.Email,
 		Password: req.Password,
-		Role:     "customer",
+		Role:     models.RoleCustomer,
 	}
 
 	if err := user.HashPassword(); err != nil {
@@ -135,9 +168,11 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 
 	// Create customer profile
 	customer := &models.Customer{
-		UserID: user.ID,
-		Name:   req.Name,
-		Phone:  req.Phone,
+		UserID:   user.ID,
+		Name:     req.Name,
+		Phone:    req.Phone,
+		Currency: preferences.Currency,
+		Country:  preferences.Country,
 	}
 
 	if err := h.db.Create(customer).Error; err != nil {
@@ -145,6 +180,8 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAuditResourceID(c, customer.ID)
+
 	// Load user relationship
 	h.db.Preload("User").First(customer, customer.ID)
 
@@ -215,6 +252,7 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		h.ErrorResponse(c, http.StatusNotFound, "Customer not found")
 		return
 	}
+	middleware.SetAuditSnapshot(c, customer)
 
 	// Update fields
 	if req.Name != "" {
@@ -223,6 +261,10 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 	if req.Phone != "" {
 		customer.Phone = req.Phone
 	}
+	if !setPricingPreferences(&customer, req.Currency, req.Country) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code and country an ISO 3166 alpha-2 code")
+		return
+	}
 
 	if err := h.db.Save(&customer).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update customer")
@@ -261,6 +303,7 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 		h.ErrorResponse(c, http.StatusNotFound, "Customer not found")
 		return
 	}
+	middleware.SetAuditSnapshot(c, customer)
 
 	if err := h.db.Delete(&customer).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete customer")
@@ -327,6 +370,10 @@ func (h *CustomerHandler) UpdateProfile(c *gin.Context) {
 	if req.Phone != "" {
 		customer.Phone = req.Phone
 	}
+	if !setPricingPreferences(customer, req.Currency, req.Country) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code and country an ISO 3166 alpha-2 code")
+		return
+	}
 
 	if err := h.db.Save(customer).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile")
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/database/databasetest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCustomerCreateAndSearch(t *testing.T) {
+	databasetest.Drivers(t, databasetest.SQLite, func(t *testing.T, db *database.DB) {
+		handler := NewCustomerHandler(db)
+		router := gin.New()
+		router.POST("/customers", handler.CreateCustomer)
+		router.GET("/customers", handler.ListCustomers)
+
+		mustCreate(t, router, "/customers",
+			CreateCustomerRequest{Email: "alice@example.com", Password: "secret1", Name: "Alice Smith"},
+			CreateCustomerRequest{Email: "bob@example.org", Password: "secret1", Name: "Bob_Jones"},
+		)
+		code, _ := perform(t, router, http.MethodPost, "/customers", CreateCustomerRequest{
+			Email: "alice@example.com", Password: "secret1", Name: "Alice Again",
+		})
+		if code != http.StatusConflict {
+			t.Fatalf("duplicate email: status %d, want %d", code, http.StatusConflict)
+		}
+
+		checkSearch(t, router, "/customers", "name", []searchCase{
+			{"", []string{"Alice Smith", "Bob_Jones"}},
+			{"ALICE", []string{"Alice Smith"}},
+			{"example.org", []string{"Bob_Jones"}},
+			{"_", []string{"Bob_Jones"}},
+			{"%", nil},
+		})
+	})
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FeatureHandler struct {
+	*BaseHandler
+}
+
+func NewFeatureHandler(db *database.DB) *FeatureHandler {
+	return &FeatureHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// CreateFeatureRequest represents a request to add a feature to the catalog
+type CreateFeatureRequest struct {
+	Key          string `json:"key" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Type         string `json:"type" binding:"required,oneof=boolean limit string"`
+	DefaultValue string `json:"default_value"`
+}
+
+// UpdateFeatureRequest represents a feature update. The key and type cannot
+// change because clients and pack values depend on them.
+type UpdateFeatureRequest struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	DefaultValue *string `json:"default_value"`
+}
+
+// PackFeatureRequest sets the value a pack grants for a feature
+type PackFeatureRequest struct {
+	Value string `json:"value"`
+}
+
+// ListFeatures handles listing the feature catalog (admin only)
+// @Summary List features
+// @Description Get every feature in the entitlement catalog
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/features [get]
+func (h *FeatureHandler) ListFeatures(c *gin.Context) {
+	var features []models.Feature
+	if err := h.db.Order("key ASC").Find(&features).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve features")
+		return
+	}
+
+	h.SuccessResponse(c, features, "")
+}
+
+// CreateFeature handles adding a feature to the catalog (admin only)
+// @Summary Create feature
+// @Description Add a boolean, limit or string feature to the entitlement catalog. Limits are integers, with -1 meaning unlimited.
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateFeatureRequest true "Feature"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/admin/features [post]
+func (h *FeatureHandler) CreateFeature(c *gin.Context) {
+	var req CreateFeatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if !models.IsValidFeatureKey(req.Key) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Key must start with a lowercase letter and contain only lowercase letters, digits, '.', '_' or '-'")
+		return
+	}
+
+	feature := models.Feature{
+		Key:          req.Key,
+		Name:         req.Name,
+		Description:  req.Description,
+		Type:         req.Type,
+		DefaultValue: req.DefaultValue,
+	}
+	if _, err := feature.ParseValue(feature.DefaultValue); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Default value does not match the feature type")
+		return
+	}
+
+	err := h.db.Create(&feature).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		h.ErrorResponse(c, http.StatusConflict, "Feature key already exists")
+		return
+	}
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create feature")
+		return
+	}
+	middleware.SetAuditResourceID(c, feature.ID)
+
+	h.SuccessResponse(c, feature, "Feature created successfully")
+}
+
+// GetFeature handles getting a feature (admin only)
+// @Summary Get feature
+// @Description Get a feature from the entitlement catalog by ID
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Feature ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/features/{id} [get]
+func (h *FeatureHandler) GetFeature(c *gin.Context) {
+	feature, ok := h.findFeature(c, "id")
+	if !ok {
+		return
+	}
+
+	h.SuccessResponse(c, feature, "")
+}
+
+// UpdateFeature handles updating a feature (admin only)
+// @Summary Update feature
+// @Description Change the name, description or default value of a feature
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Feature ID"
+// @Param request body UpdateFeatureRequest true "Fields to update"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/features/{id} [put]
+func (h *FeatureHandler) UpdateFeature(c *gin.Context) {
+	var req UpdateFeatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	feature, ok := h.findFeature(c, "id")
+	if !ok {
+		return
+	}
+	middleware.SetAuditSnapshot(c, *feature)
+
+	if req.Name != "" {
+		feature.Name = req.Name
+	}
+	if req.Description != "" {
+		feature.Description = req.Description
+	}
+	if req.DefaultValue != nil {
+		if _, err := feature.ParseValue(*req.DefaultValue); err != nil {
+			h.ErrorResponse(c, http.StatusBadRequest, "Default value does not match the feature type")
+			return
+		}
+		feature.DefaultValue = *req.DefaultValue
+	}
+
+	if err := h.db.Save(feature).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update feature")
+		return
+	}
+
+	h.SuccessResponse(c, feature, "Feature updated successfully")
+}
+
+// DeleteFeature handles removing a feature and every pack value for it (admin only)
+// @Summary Delete feature
+// @Description Remove a feature from the catalog together with the values packs grant for it
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Feature ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/features/{id} [delete]
+func (h *FeatureHandler) DeleteFeature(c *gin.Context) {
+	feature, ok := h.findFeature(c, "id")
+	if !ok {
+		return
+	}
+	middleware.SetAuditSnapshot(c, *feature)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("feature_id = ?", feature.ID).Delete(&models.PackFeature{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(feature).Error
+	})
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete feature")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Feature deleted successfully"}, "")
+}
+
+// ListPackFeatures handles listing the feature values a pack grants (admin only)
+// @Summary List pack features
+// @Description Get the feature values set on a subscription pack. Features without a value resolve to their default.
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription Pack ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/packs/{id}/features [get]
+func (h *FeatureHandler) ListPackFeatures(c *gin.Context) {
+	pack, ok := h.findPack(c)
+	if !ok {
+		return
+	}
+
+	var values []models.PackFeature
+	if err := h.db.Preload("Feature").Where("pack_id = ?", pack.ID).Order("feature_id ASC").Find(&values).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve pack features")
+		return
+	}
+
+	h.SuccessResponse(c, values, "")
+}
+
+// SetPackFeature handles setting the value a pack grants for a feature (admin only)
+// @Summary Set pack feature
+// @Description Set or replace the value a subscription pack grants for a feature
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription Pack ID"
+// @Param feature_id path int true "Feature ID"
+// @Param request body PackFeatureRequest true "Feature value"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/packs/{id}/features/{feature_id} [put]
+func (h *FeatureHandler) SetPackFeature(c *gin.Context) {
+	var req PackFeatureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	pack, ok := h.findPack(c)
+	if !ok {
+		return
+	}
+	feature, ok := h.findFeature(c, "feature_id")
+	if !ok {
+		return
+	}
+	if _, err := feature.ParseValue(req.Value); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Value does not match the feature type")
+		return
+	}
+
+	var value models.PackFeature
+	err := h.db.Where("pack_id = ? AND feature_id = ?", pack.ID, feature.ID).First(&value).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to set pack feature")
+		return
+	}
+	if err == nil {
+		middleware.SetAuditSnapshot(c, value)
+	}
+
+	value.PackID = pack.ID
+	value.FeatureID = feature.ID
+	value.Value = req.Value
+	if err := h.db.Save(&value).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to set pack feature")
+		return
+	}
+	value.Feature = feature
+
+	h.SuccessResponse(c, value, "Pack feature set successfully")
+}
+
+// RemovePackFeature handles removing a pack's value for a feature (admin only)
+// @Summary Remove pack feature
+// @Description Remove the value a subscription pack grants for a feature, so it falls back to the feature default
+// @Tags Admin Features
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription Pack ID"
+// @Param feature_id path int true "Feature ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/packs/{id}/features/{feature_id} [delete]
+func (h *FeatureHandler) RemovePackFeature(c *gin.Context) {
+	packID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack ID")
+		return
+	}
+	featureID, err := strconv.ParseUint(c.Param("feature_id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid feature ID")
+		return
+	}
+
+	var value models.PackFeature
+	if err := h.db.Where("pack_id = ? AND feature_id = ?", packID, featureID).First(&value).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Pack feature not found")
+		return
+	}
+	middleware.SetAuditSnapshot(c, value)
+
+	if err := h.db.Delete(&value).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove pack feature")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Pack feature removed successfully"}, "")
+}
+
+func (h *FeatureHandler) findFeature(c *gin.Context, param string) (*models.Feature, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid feature ID")
+		return nil, false
+	}
+
+	var feature models.Feature
+	if err := h.db.First(&feature, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Feature not found")
+		return nil, false
+	}
+	return &feature, true
+}
+
+func (h *FeatureHandler) findPack(c *gin.Context) (*models.SubscriptionPack, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack ID")
+		return nil, false
+	}
+
+	var pack models.SubscriptionPack
+	if err := h.db.First(&pack, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Subscription pack not found")
+		return nil, false
+	}
+	return &pack, true
+}
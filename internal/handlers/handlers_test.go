@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testResponse is the JSON envelope written by BaseHandler
+type testResponse struct {
+	Success    bool            `json:"success"`
+	Data       json.RawMessage `json:"data"`
+	Error      string          `json:"error"`
+	Pagination struct {
+		Total int64 `json:"total"`
+	} `json:"pagination"`
+}
+
+// perform sends a request with an optional JSON body through router and
+// decodes the response envelope
+func perform(t *testing.T, router http.Handler, method, path string, body interface{}) (int, testResponse) {
+	t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatalf("encode request: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	var response testResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: decode response %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+// decodeData unmarshals the response data into v
+func decodeData(t *testing.T, response testResponse, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(response.Data, v); err != nil {
+		t.Fatalf("decode data %s: %v", response.Data, err)
+	}
+}
+
+// mustCreate posts each body to path and fails the test unless it succeeds
+func mustCreate(t *testing.T, router http.Handler, path string, bodies ...interface{}) {
+	t.Helper()
+	for _, body := range bodies {
+		code, response := perform(t, router, http.MethodPost, path, body)
+		if code != http.StatusOK {
+			t.Fatalf("create %+v: status %d: %s", body, code, response.Error)
+		}
+	}
+}
+
+// searchCase is a search term and the names a list must return for it
+type searchCase struct {
+	search string
+	want   []string
+}
+
+// checkSearch lists path with each search term and checks that exactly the
+// wanted items come back, identified by their field in the JSON response.
+// Lists are unordered, so names are compared sorted.
+func checkSearch(t *testing.T, router http.Handler, path, field string, cases []searchCase) {
+	t.Helper()
+	for _, tt := range cases {
+		code, response := perform(t, router, http.MethodGet, path+"?search="+url.QueryEscape(tt.search), nil)
+		if code != http.StatusOK {
+			t.Fatalf("search %q: status %d: %s", tt.search, code, response.Error)
+		}
+
+		var items []map[string]interface{}
+		decodeData(t, response, &items)
+		got := make([]string, len(items))
+		for i, item := range items {
+			got[i], _ = item[field].(string)
+		}
+		sort.Strings(got)
+		want := append([]string{}, tt.want...)
+		sort.Strings(want)
+
+		if strings.Join(got, "\n") != strings.Join(want, "\n") || response.Pagination.Total != int64(len(want)) {
+			t.Errorf("search %q: got %q (total %d), want %q", tt.search, got, response.Pagination.Total, want)
+		}
+	}
+}
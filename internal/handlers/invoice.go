@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// invoiceDocument renders an invoice as a standalone HTML page. Printing it
+// from a browser (or any HTML to PDF converter) gives the PDF copy.
+var invoiceDocument = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount": models.FormatAmount,
+	"date": func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format("2006-01-02")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 800px; margin: 40px auto; padding: 0 20px; }
+h1 { font-size: 24px; margin-bottom: 4px; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+tfoot td { border-bottom: none; }
+.meta td { border-bottom: none; padding: 2px 8px 2px 0; }
+.status { text-transform: uppercase; font-weight: bold; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p class="status">{{.Status}}</p>
+<table class="meta">
+<tr><td>Billed to</td><td>{{with .Customer}}{{.Name}}{{with .User}} &lt;{{.Email}}&gt;{{end}}{{end}}</td></tr>
+<tr><td>Issued</td><td>{{date .IssuedAt}}</td></tr>
+<tr><td>Due</td><td>{{date .DueAt}}</td></tr>
+<tr><td>Period</td><td>{{date .PeriodStart}} to {{date .PeriodEnd}}</td></tr>
+{{if .PaidAt}}<tr><td>Paid</td><td>{{date .PaidAt}}</td></tr>{{end}}
+{{if .VoidedAt}}<tr><td>Voided</td><td>{{date .VoidedAt}}</td></tr>{{end}}
+</table>
+<table>
+<thead><tr><th>Description</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Amount</th></tr></thead>
+<tbody>
+{{range .Lines}}<tr><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{amount .UnitAmount $.Currency}}</td><td class="num">{{amount .Amount $.Currency}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+<tr><td colspan="3" class="num">Subtotal</td><td class="num">{{amount .Subtotal .Currency}}</td></tr>
+<tr><td colspan="3" class="num">Tax ({{.TaxRate}}%)</td><td class="num">{{amount .TaxAmount .Currency}}</td></tr>
+<tr><td colspan="3" class="num"><strong>Total</strong></td><td class="num"><strong>{{amount .Total .Currency}}</strong></td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+type InvoiceHandler struct {
+	*BaseHandler
+	invoices *services.InvoiceService
+}
+
+func NewInvoiceHandler(db *database.DB, invoices *services.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		BaseHandler: NewBaseHandler(db),
+		invoices:    invoices,
+	}
+}
+
+// ListInvoices handles listing all invoices (admin only)
+// @Summary List invoices
+// @Description Get paginated invoices, newest first
+// @Tags Admin Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Filter by status (draft, open, paid, void)"
+// @Param customer_id query int false "Filter by customer ID"
+// @Param subscription_id query int false "Filter by subscription ID"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/invoices [get]
+func (h *InvoiceHandler) ListInvoices(c *gin.Context) {
+	query := h.db.Model(&models.Invoice{})
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	for _, filter := range []string{"customer_id", "subscription_id"} {
+		if value := c.Query(filter); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				h.ErrorResponse(c, http.StatusBadRequest, "Invalid "+filter)
+				return
+			}
+			query = query.Where(filter+" = ?", id)
+		}
+	}
+
+	h.listInvoices(c, query.Preload("Customer.User"))
+}
+
+// GetInvoice handles getting an invoice with its lines (admin only)
+// @Summary Get invoice
+// @Description Get an invoice by ID with its line items
+// @Tags Admin Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/invoices/{id} [get]
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	invoice, ok := h.loadInvoice(c, h.db.DB)
+	if !ok {
+		return
+	}
+
+	h.SuccessResponse(c, invoice, "")
+}
+
+// GetInvoiceDocument handles rendering an invoice for printing (admin only)
+// @Summary Get invoice document
+// @Description Render an invoice as a print-ready HTML document; print it to get a PDF
+// @Tags Admin Invoices
+// @Produce html
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {string} string "HTML document"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/invoices/{id}/document [get]
+func (h *InvoiceHandler) GetInvoiceDocument(c *gin.Context) {
+	invoice, ok := h.loadInvoice(c, h.db.DB)
+	if !ok {
+		return
+	}
+
+	h.renderDocument(c, invoice)
+}
+
+// MarkInvoicePaid handles recording payment of an open invoice (admin only)
+// @Summary Mark invoice paid
+// @Description Record payment of an open invoice
+// @Tags Admin Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/invoices/{id}/pay [put]
+func (h *InvoiceHandler) MarkInvoicePaid(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+
+	invoice, err := h.invoices.MarkPaid(uint(id))
+	if err != nil {
+		h.invoiceError(c, err, "Only open invoices can be marked paid")
+		return
+	}
+
+	h.SuccessResponse(c, invoice, "Invoice marked paid")
+}
+
+// VoidInvoice handles cancelling an unpaid invoice (admin only)
+// @Summary Void invoice
+// @Description Void a draft or open invoice. Paid invoices cannot be voided.
+// @Tags Admin Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/invoices/{id}/void [put]
+func (h *InvoiceHandler) VoidInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+
+	invoice, err := h.invoices.Void(uint(id))
+	if err != nil {
+		h.invoiceError(c, err, "Only draft or open invoices can be voided")
+		return
+	}
+
+	h.SuccessResponse(c, invoice, "Invoice voided")
+}
+
+// ListMyInvoices handles listing the current customer's invoices
+// @Summary List my invoices
+// @Description Get the current customer's invoices, newest first
+// @Tags Customer Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} PaginatedResponse
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/customer/invoices [get]
+func (h *InvoiceHandler) ListMyInvoices(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	h.listInvoices(c, h.db.Model(&models.Invoice{}).Where("customer_id = ?", customer.ID))
+}
+
+// GetMyInvoice handles getting one of the current customer's invoices
+// @Summary Get my invoice
+// @Description Get one of the current customer's invoices with its line items
+// @Tags Customer Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/invoices/{id} [get]
+func (h *InvoiceHandler) GetMyInvoice(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	invoice, ok := h.loadInvoice(c, h.db.Where("customer_id = ?", customer.ID))
+	if !ok {
+		return
+	}
+
+	h.SuccessResponse(c, invoice, "")
+}
+
+// GetMyInvoiceDocument handles rendering one of the current customer's invoices for printing
+// @Summary Get my invoice document
+// @Description Render one of the current customer's invoices as a print-ready HTML document; print it to get a PDF
+// @Tags Customer Invoices
+// @Produce html
+// @Security BearerAuth
+// @Param id path int true "Invoice ID"
+// @Success 200 {string} string "HTML document"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/invoices/{id}/document [get]
+func (h *InvoiceHandler) GetMyInvoiceDocument(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	invoice, ok := h.loadInvoice(c, h.db.Where("customer_id = ?", customer.ID))
+	if !ok {
+		return
+	}
+
+	h.renderDocument(c, invoice)
+}
+
+// listInvoices writes one page of the invoices matched by query
+func (h *InvoiceHandler) listInvoices(c *gin.Context, query *gorm.DB) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	var total int64
+	query.Count(&total)
+
+	var invoices []models.Invoice
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&invoices).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invoices")
+		return
+	}
+
+	h.PaginatedResponse(c, invoices, total, page, limit)
+}
+
+// loadInvoice loads the invoice named by the id parameter from query, writing
+// the error response and returning false if it cannot
+func (h *InvoiceHandler) loadInvoice(c *gin.Context, query *gorm.DB) (*models.Invoice, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid invoice ID")
+		return nil, false
+	}
+
+	var invoice models.Invoice
+	err = query.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Customer.User").First(&invoice, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.ErrorResponse(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invoice")
+		}
+		return nil, false
+	}
+	return &invoice, true
+}
+
+func (h *InvoiceHandler) renderDocument(c *gin.Context, invoice *models.Invoice) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := invoiceDocument.Execute(c.Writer, invoice); err != nil {
+		c.Error(err)
+	}
+}
+
+// invoiceError writes the response for a failed invoice status change
+func (h *InvoiceHandler) invoiceError(c *gin.Context, err error, transitionMessage string) {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Invoice not found")
+	case errors.Is(err, services.ErrInvalidInvoiceTransition):
+		h.ErrorResponse(c, http.StatusBadRequest, transitionMessage)
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update invoice")
+	}
+}
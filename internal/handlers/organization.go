@@ -0,0 +1,647 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationHandler struct {
+	*BaseHandler
+	organizations *services.OrganizationService
+}
+
+func NewOrganizationHandler(db *database.DB, organizations *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		BaseHandler:   NewBaseHandler(db),
+		organizations: organizations,
+	}
+}
+
+// OrganizationRequest names an organization
+type OrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// InviteMemberRequest invites an email address to an organization
+type InviteMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=admin member"`
+}
+
+// InviteMemberResponse carries the invitation token, which is only shown once
+type InviteMemberResponse struct {
+	Token      string                         `json:"token"`
+	Invitation *models.OrganizationInvitation `json:"invitation"`
+}
+
+// MemberRoleRequest changes a member's role
+type MemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+// AcceptInvitationRequest accepts an organization invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// MyOrganization is an organization together with the current customer's role
+type MyOrganization struct {
+	models.Organization
+	Role string `json:"role"`
+}
+
+// ListMyOrganizations handles listing the organizations the customer belongs to
+// @Summary List my organizations
+// @Description Get the organizations the current customer is a member of, with their role in each
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/customer/organizations [get]
+func (h *OrganizationHandler) ListMyOrganizations(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var memberships []models.OrganizationMember
+	if err := h.db.Preload("Organization").Where("customer_id = ?", customer.ID).Order("organization_id ASC").Find(&memberships).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve organizations")
+		return
+	}
+
+	organizations := make([]MyOrganization, 0, len(memberships))
+	for _, m := range memberships {
+		if m.Organization != nil {
+			organizations = append(organizations, MyOrganization{Organization: *m.Organization, Role: m.Role})
+		}
+	}
+
+	h.SuccessResponse(c, organizations, "")
+}
+
+// CreateOrganization handles creating an organization owned by the customer
+// @Summary Create organization
+// @Description Create an organization with the current customer as its owner. The organization's subscriptions are billed to the owner.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body OrganizationRequest true "Organization"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/customer/organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	organization, err := h.organizations.Create(customer.ID, req.Name)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create organization")
+		return
+	}
+
+	h.SuccessResponse(c, organization, "Organization created successfully")
+}
+
+// GetOrganization handles getting one of the customer's organizations
+// @Summary Get organization
+// @Description Get an organization the current customer is a member of, with its members
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} models.Organization
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+
+	organization := member.Organization
+	if err := h.db.Preload("Members.Customer.User").First(organization, organization.ID).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve organization")
+		return
+	}
+
+	h.SuccessResponse(c, organization, "")
+}
+
+// UpdateOrganization handles renaming an organization
+// @Summary Update organization
+// @Description Rename an organization (owners and admins)
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body OrganizationRequest true "Organization"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		h.organizationError(c, services.ErrNotOrganizationManager)
+		return
+	}
+
+	organization := member.Organization
+	if err := h.db.Model(organization).Update("name", req.Name).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update organization")
+		return
+	}
+
+	h.SuccessResponse(c, organization, "Organization updated successfully")
+}
+
+// DeleteOrganization handles deleting an organization
+// @Summary Delete organization
+// @Description Delete an organization with its members and invitations (owner only). Its subscriptions must have ended first.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+
+	if err := h.organizations.Delete(member); err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Organization deleted successfully"}, "")
+}
+
+// SetMemberRole handles changing a member's role
+// @Summary Set member role
+// @Description Make another member an admin or a plain member (owners and admins). Only the owner can change an admin's role, and the owner's role cannot change.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param customer_id path int true "Member's customer ID"
+// @Param request body MemberRoleRequest true "Role"
+// @Success 200 {object} models.OrganizationMember
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/members/{customer_id} [put]
+func (h *OrganizationHandler) SetMemberRole(c *gin.Context) {
+	var req MemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+	customerID, err := strconv.ParseUint(c.Param("customer_id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	updated, err := h.organizations.SetRole(member, uint(customerID), req.Role)
+	if err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, updated, "Member role updated successfully")
+}
+
+// RemoveMember handles removing a member, or leaving the organization
+// @Summary Remove member
+// @Description Remove a member from an organization. Owners and admins can remove members, only the owner can remove admins, and any member but the owner can remove themselves.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param customer_id path int true "Member's customer ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/members/{customer_id} [delete]
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+	customerID, err := strconv.ParseUint(c.Param("customer_id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid customer ID")
+		return
+	}
+
+	if err := h.organizations.RemoveMember(member, uint(customerID)); err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Member removed successfully"}, "")
+}
+
+// ListInvitations handles listing an organization's pending invitations
+// @Summary List invitations
+// @Description Get the pending invitations of an organization (owners and admins)
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/invitations [get]
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		h.organizationError(c, services.ErrNotOrganizationManager)
+		return
+	}
+
+	var invitations []models.OrganizationInvitation
+	err := h.db.Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", member.OrganizationID, time.Now()).
+		Order("id DESC").Find(&invitations).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve invitations")
+		return
+	}
+
+	h.SuccessResponse(c, invitations, "")
+}
+
+// InviteMember handles inviting an email address to an organization
+// @Summary Invite member
+// @Description Invite an email address to join an organization as a member (the default) or admin (owners and admins). The token is returned once and sent in the organization.invitation_created webhook for delivery by email. Members and pending invitations together are limited to the max_seats of the organization's current subscription pack.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body InviteMemberRequest true "Invitation"
+// @Success 200 {object} InviteMemberResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/invitations [post]
+func (h *OrganizationHandler) InviteMember(c *gin.Context) {
+	var req InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.OrgRoleMember
+	}
+
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+
+	invitation, token, err := h.organizations.Invite(member, req.Email, req.Role)
+	if err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, InviteMemberResponse{Token: token, Invitation: invitation}, "Invitation created. Send the token to the invitee; it will not be shown again.")
+}
+
+// RevokeInvitation handles withdrawing a pending invitation
+// @Summary Revoke invitation
+// @Description Withdraw a pending invitation, freeing its seat (owners and admins)
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param invitation_id path int true "Invitation ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/invitations/{invitation_id} [delete]
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+	invitationID, err := strconv.ParseUint(c.Param("invitation_id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	if err := h.organizations.RevokeInvitation(member, uint(invitationID)); err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Invitation revoked successfully"}, "")
+}
+
+// AcceptInvitation handles joining an organization with an invitation token
+// @Summary Accept invitation
+// @Description Join the organization an invitation token was issued for. The invitation must have been sent to the current customer's email address.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AcceptInvitationRequest true "Invitation token"
+// @Success 200 {object} models.OrganizationMember
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/customer/invitations/accept [post]
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	user, err := h.GetCurrentUser(c)
+	if err != nil || user.Customer == nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	member, err := h.organizations.AcceptInvitation(user.Customer, user.Email, req.Token)
+	if err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, member, "Invitation accepted")
+}
+
+// GetOrganizationSubscription handles getting an organization's current subscription
+// @Summary Get organization subscription
+// @Description Get the current subscription of an organization the customer is a member of
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} models.Subscription
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/subscription [get]
+func (h *OrganizationHandler) GetOrganizationSubscription(c *gin.Context) {
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.organizations.CurrentSubscription(member.OrganizationID)
+	if err != nil {
+		h.organizationError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, subscription, "")
+}
+
+// RequestOrganizationSubscription handles requesting a subscription for an organization
+// @Summary Request organization subscription
+// @Description Request a subscription owned by the organization (owners and admins), optionally redeeming a coupon code. It is billed to the owner and, once assigned, licenses every member; its pack's max_seats limits how many members the organization can have.
+// @Tags Customer Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body SubscriptionRequest true "Subscription request"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/customer/organizations/{id}/subscription/request [post]
+func (h *OrganizationHandler) RequestOrganizationSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	member, ok := h.membership(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.organizations.RequestSubscription(member, req.PackSKU, req.CouponCode, h.CurrentActor(c, models.ActorCustomer))
+	switch {
+	case errors.Is(err, services.ErrNotOrganizationManager):
+		h.organizationError(c, err)
+		return
+	case errors.Is(err, services.ErrActiveSubscriptionExists):
+		h.ErrorResponse(c, http.StatusConflict, "Organization already has an active subscription")
+		return
+	case err != nil:
+		h.requestError(c, err)
+		return
+	}
+
+	// Load pack information
+	h.db.Preload("Pack").Preload("Coupon").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription request created successfully")
+}
+
+// ListOrganizations handles listing organizations (admin only)
+// @Summary List organizations
+// @Description Get paginated organizations with their owner
+// @Tags Admin Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param search query string false "Search by name"
+// @Success 200 {object} PaginatedResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/organizations [get]
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Organization{})
+	if search := c.Query("search"); search != "" {
+		condition, args := database.SearchCondition(search, "name")
+		query = query.Where(condition, args...)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var organizations []models.Organization
+	if err := query.Preload("Customer.User").Order("id DESC").Offset(offset).Limit(limit).Find(&organizations).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve organizations")
+		return
+	}
+
+	h.PaginatedResponse(c, organizations, total, page, limit)
+}
+
+// GetOrganizationAdmin handles getting an organization (admin only)
+// @Summary Get organization
+// @Description Get an organization with its owner and members
+// @Tags Admin Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganizationAdmin(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid organization ID")
+		return
+	}
+
+	var organization models.Organization
+	if err := h.db.Preload("Customer.User").Preload("Members.Customer.User").First(&organization, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	h.SuccessResponse(c, organization, "")
+}
+
+// membership loads the current customer's membership of the organization in
+// the id path parameter, writing an error response and returning false when
+// they are not a member
+func (h *OrganizationHandler) membership(c *gin.Context) (*models.OrganizationMember, bool) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return nil, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid organization ID")
+		return nil, false
+	}
+
+	member, err := h.organizations.Membership(uint(id), customer.ID)
+	if err != nil {
+		h.organizationError(c, err)
+		return nil, false
+	}
+	return member, true
+}
+
+// organizationError writes the response for a failed organization change
+func (h *OrganizationHandler) organizationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Organization not found")
+	case errors.Is(err, services.ErrNotOrganizationManager):
+		h.ErrorResponse(c, http.StatusForbidden, "Only organization owners and admins can do this")
+	case errors.Is(err, services.ErrOrganizationOwner):
+		h.ErrorResponse(c, http.StatusBadRequest, "The organization owner cannot be changed or removed")
+	case errors.Is(err, services.ErrOrganizationHasSubscription):
+		h.ErrorResponse(c, http.StatusConflict, "The organization still has an open subscription")
+	case errors.Is(err, services.ErrMemberNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Member not found")
+	case errors.Is(err, services.ErrAlreadyMember):
+		h.ErrorResponse(c, http.StatusConflict, "Already a member of the organization")
+	case errors.Is(err, services.ErrAlreadyInvited):
+		h.ErrorResponse(c, http.StatusConflict, "This email already has a pending invitation")
+	case errors.Is(err, services.ErrNoMemberSeats):
+		h.ErrorResponse(c, http.StatusConflict, "No member seats left on the organization's subscription")
+	case errors.Is(err, services.ErrInvitationNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+	case errors.Is(err, services.ErrInvitationExpired):
+		h.ErrorResponse(c, http.StatusGone, "Invitation has expired or was already accepted")
+	case errors.Is(err, services.ErrInvitationEmailMismatch):
+		h.ErrorResponse(c, http.StatusForbidden, "This invitation was sent to another email address")
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update organization")
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/payments"
+	"cursor-ai-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBody bounds the payment webhook bodies that are read
+const maxWebhookBody = 64 << 10
+
+// fakeCheckoutPage is the hosted checkout page of the fake payment provider
+var fakeCheckoutPage = template.Must(template.New("checkout").Funcs(template.FuncMap{
+	"amount": models.FormatAmount,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Fake checkout</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 480px; margin: 60px auto; padding: 0 20px; }
+form { display: inline-block; margin-right: 8px; }
+button { font-size: 16px; padding: 8px 16px; }
+</style>
+</head>
+<body>
+<h1>Fake checkout</h1>
+<p>This page stands in for a payment provider. No money is taken.</p>
+<p>{{.Request.Description}}{{with .Request.CustomerEmail}} for {{.}}{{end}}</p>
+<p><strong>{{amount .Request.Amount .Request.Currency}}</strong></p>
+<form method="post" action="/payments/fake/checkout/{{.SessionID}}/pay"><button type="submit">Pay</button></form>
+<form method="post" action="/payments/fake/checkout/{{.SessionID}}/decline"><button type="submit">Decline</button></form>
+</body>
+</html>
+`))
+
+type PaymentHandler struct {
+	*BaseHandler
+	paymentService *services.PaymentService
+	fake           *payments.FakeProvider
+}
+
+// NewPaymentHandler creates the handler. fake is the fake provider whose
+// checkout page is served, or nil when it is not in use.
+func NewPaymentHandler(db *database.DB, paymentService *services.PaymentService, fake *payments.FakeProvider) *PaymentHandler {
+	return &PaymentHandler{
+		BaseHandler:    NewBaseHandler(db),
+		paymentService: paymentService,
+		fake:           fake,
+	}
+}
+
+// FakeEnabled reports whether the fake provider's checkout pages are served
+func (h *PaymentHandler) FakeEnabled() bool {
+	return h.fake != nil
+}
+
+// CheckoutRequest represents a checkout for a requested subscription
+type CheckoutRequest struct {
+	SubscriptionID uint   `json:"subscription_id"`
+	SuccessURL     string `json:"success_url" binding:"omitempty,url"`
+	CancelURL      string `json:"cancel_url" binding:"omitempty,url"`
+}
+
+// CreateCheckout starts a payment for a requested subscription
+// @Summary Start checkout
+// @Description Start a hosted checkout for a requested subscription (the latest one if subscription_id is omitted). Send the customer to checkout_url; once the payment is confirmed the subscription is approved and assigned automatically. A subscription with a successful payment, or a pending one whose session has not expired (CHECKOUT_SESSION_TTL), cannot be checked out again.
+// @Tags Customer Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CheckoutRequest true "Checkout request"
+// @Success 200 {object} models.Payment
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/v1/customer/subscription/checkout [post]
+func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	payment, err := h.paymentService.Checkout(c.Request.Context(), customer.ID, req.SubscriptionID, req.SuccessURL, req.CancelURL)
+	switch {
+	case errors.Is(err, services.ErrPaymentsDisabled):
+		h.ErrorResponse(c, http.StatusServiceUnavailable, "Online payment is not available")
+		return
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "No requested subscription found")
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusBadRequest, "Only requested subscriptions can be paid for")
+		return
+	case errors.Is(err, services.ErrPackNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Subscription pack is no longer available")
+		return
+	case errors.Is(err, services.ErrCheckoutInProgress):
+		h.ErrorResponse(c, http.StatusConflict, "A checkout for this subscription is already in progress")
+		return
+	case errors.Is(err, services.ErrAlreadyPaid):
+		h.ErrorResponse(c, http.StatusConflict, "This subscription has already been paid for")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to start checkout")
+		return
+	}
+
+	h.SuccessResponse(c, payment, "Checkout started")
+}
+
+// HandleWebhook receives payment events from a provider
+// @Summary Payment provider webhook
+// @Description Receive a signed payment event. A successful payment approves and assigns its subscription and marks the invoice paid. Redeliveries are acknowledged without being applied twice.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name, e.g. fake"
+// @Success 200 {object} models.Payment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /payments/webhooks/{provider} [post]
+func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	payment, err := h.paymentService.HandleWebhook(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		h.webhookError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, payment, "Event processed")
+}
+
+// ListPayments handles listing payments (admin only)
+// @Summary List payments
+// @Description Get paginated checkout payments, newest first
+// @Tags Admin Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Filter by status (pending, succeeded, failed, expired)"
+// @Param customer_id query int false "Filter by customer ID"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/payments [get]
+func (h *PaymentHandler) ListPayments(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Payment{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if customerID := c.Query("customer_id"); customerID != "" {
+		id, err := strconv.ParseUint(customerID, 10, 32)
+		if err != nil {
+			h.ErrorResponse(c, http.StatusBadRequest, "Invalid customer_id")
+			return
+		}
+		query = query.Where("customer_id = ?", id)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var list []models.Payment
+	if err := query.Preload("Customer.User").Order("id DESC").Offset(offset).Limit(limit).Find(&list).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve payments")
+		return
+	}
+
+	h.PaginatedResponse(c, list, total, page, limit)
+}
+
+// FakeCheckout serves the fake provider's hosted checkout page
+// @Summary Fake checkout page
+// @Description Checkout page of the built-in fake payment provider, for local testing
+// @Tags Payments
+// @Produce html
+// @Param session_id path string true "Checkout session ID"
+// @Success 200 {string} string "HTML page"
+// @Failure 404 {object} map[string]string
+// @Router /payments/fake/checkout/{session_id} [get]
+func (h *PaymentHandler) FakeCheckout(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	req, ok := h.fake.Session(sessionID)
+	if !ok {
+		h.ErrorResponse(c, http.StatusNotFound, "Checkout session not found, expired or already completed")
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := fakeCheckoutPage.Execute(c.Writer, gin.H{"SessionID": sessionID, "Request": req}); err != nil {
+		c.Error(err)
+	}
+}
+
+// CompleteFakeCheckout settles a fake checkout session
+// @Summary Complete fake checkout
+// @Description Pay or decline a fake checkout session. The fake provider signs the resulting webhook event, which is then handled exactly like a provider webhook. Redirects to the checkout's success or cancel URL when one was given.
+// @Tags Payments
+// @Produce json
+// @Param session_id path string true "Checkout session ID"
+// @Param result path string true "pay or decline"
+// @Success 200 {object} models.Payment
+// @Success 303 {string} string "Redirect to the success or cancel URL"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /payments/fake/checkout/{session_id}/{result} [post]
+func (h *PaymentHandler) CompleteFakeCheckout(c *gin.Context) {
+	result := c.Param("result")
+	if result != "pay" && result != "decline" {
+		h.ErrorResponse(c, http.StatusBadRequest, "Result must be pay or decline")
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	req, _ := h.fake.Session(sessionID)
+	body, header, err := h.fake.Complete(sessionID, result == "pay")
+	if err != nil {
+		if errors.Is(err, payments.ErrUnknownSession) {
+			h.ErrorResponse(c, http.StatusNotFound, "Checkout session not found, expired or already completed")
+		} else {
+			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to complete checkout")
+		}
+		return
+	}
+
+	payment, err := h.paymentService.HandleWebhook(h.fake.Name(), header, body)
+	if err != nil {
+		h.webhookError(c, err)
+		return
+	}
+
+	redirect := req.CancelURL
+	if payment.Status == models.PaymentSucceeded {
+		redirect = req.SuccessURL
+	}
+	if redirect != "" {
+		c.Redirect(http.StatusSeeOther, redirect)
+		return
+	}
+	h.SuccessResponse(c, payment, "Checkout completed")
+}
+
+// webhookError writes the response for a payment event that was not applied.
+// Providers retry on failure, so only errors that a retry may fix are 5xx.
+func (h *PaymentHandler) webhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownProvider):
+		h.ErrorResponse(c, http.StatusNotFound, "Unknown payment provider")
+	case errors.Is(err, payments.ErrInvalidSignature):
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook signature")
+	case errors.Is(err, payments.ErrMalformedEvent):
+		h.ErrorResponse(c, http.StatusBadRequest, "Malformed webhook event")
+	case errors.Is(err, services.ErrPaymentNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Payment not found")
+	case errors.Is(err, services.ErrPaymentAmountMismatch):
+		h.ErrorResponse(c, http.StatusBadRequest, "Paid amount does not match the checkout")
+	case errors.Is(err, services.ErrActiveSubscriptionExists):
+		h.ErrorResponse(c, http.StatusConflict, "Payment recorded, but the customer already has an active subscription")
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to process payment event")
+	}
+}
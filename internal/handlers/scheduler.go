@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SchedulerHandler struct {
+	*BaseHandler
+	expiry *scheduler.ExpiryScheduler
+}
+
+func NewSchedulerHandler(db *database.DB, expiry *scheduler.ExpiryScheduler) *SchedulerHandler {
+	return &SchedulerHandler{
+		BaseHandler: NewBaseHandler(db),
+		expiry:      expiry,
+	}
+}
+
+// GetExpiryStatus returns the last-run status of the expiry scheduler (admin only)
+// @Summary Get expiry scheduler status
+// @Description Get the last-run status of the background subscription expiry scheduler
+// @Tags Admin System
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/scheduler/expiry [get]
+func (h *SchedulerHandler) GetExpiryStatus(c *gin.Context) {
+	h.SuccessResponse(c, h.expiry.Status(), "Expiry scheduler status retrieved")
+}
@@ -1,29 +1,72 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
 	"cursor-ai-backend/internal/database"
 	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
+	"cursor-ai-backend/pkg/license"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type SDKHandler struct {
 	*BaseHandler
+	subscriptions *services.SubscriptionService
+	entitlements  *services.EntitlementService
+	signer        *license.Signer
+	verifier      *license.Verifier
+	loginKeyTTL   time.Duration
 }
 
-func NewSDKHandler(db *database.DB) *SDKHandler {
+// NewSDKHandler creates the SDK handler. signer may be nil, in which case
+// license file issuance is disabled. Keys issued by SDK login expire after
+// loginKeyTTL.
+func NewSDKHandler(db *database.DB, subscriptions *services.SubscriptionService, entitlements *services.EntitlementService, signer *license.Signer, verifier *license.Verifier, loginKeyTTL time.Duration) *SDKHandler {
 	return &SDKHandler{
-		BaseHandler: NewBaseHandler(db),
+		BaseHandler:   NewBaseHandler(db),
+		subscriptions: subscriptions,
+		entitlements:  entitlements,
+		signer:        signer,
+		verifier:      verifier,
+		loginKeyTTL:   loginKeyTTL,
 	}
 }
 
+// SDKSubscriptionResponse is the current subscription with the access flags
+// an SDK needs to decide whether to grant access
+type SDKSubscriptionResponse struct {
+	*models.Subscription
+	Access    bool `json:"access"`
+	InGrace   bool `json:"in_grace"`
+	Suspended bool `json:"suspended"`
+}
+
+// EntitlementsResponse lists the features granted by the customer's current subscription
+type EntitlementsResponse struct {
+	SubscriptionID uint                 `json:"subscription_id"`
+	PackSKU        string               `json:"pack_sku"`
+	Entitlements   []models.Entitlement `json:"entitlements"`
+}
+
+// LicenseKeyResponse represents a public key that verifies license files
+type LicenseKeyResponse struct {
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	PublicKey string `json:"public_key"`
+	Current   bool   `json:"current"`
+}
+
 // SDKLogin handles SDK authentication and returns API key
 // @Summary SDK login
-// @Description Authenticate user for SDK access and return API key
+// @Description Authenticate user for SDK access and return a newly issued API key that expires after SDK_LOGIN_KEY_TTL. Keys from earlier logins stay valid until they expire, so each machine can keep its own.
 // @Tags SDK Authentication
 // @Accept json
 // @Produce json
@@ -40,7 +83,7 @@ func (h *SDKHandler) Login(c *gin.Context) {
 	}
 
 	var user models.User
-	err := h.db.Preload("Customer").Where("email = ? AND role = ?", req.Email, "customer").First(&user).Error
+	err := h.db.Preload("Customer").Where("email = ? AND role = ?", req.Email, models.RoleCustomer).First(&user).Error
 	if err != nil {
 		h.ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
 		return
@@ -51,41 +94,48 @@ func (h *SDKHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Generate API key if user doesn't have one
-	if !user.HasAPIKey() {
-		if err := user.GenerateAPIKey(); err != nil {
-			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
-			return
-		}
+	// Issue a key for this machine; the plaintext key is only returned here
+	expiresAt := time.Now().Add(h.loginKeyTTL)
+	key, plaintext, err := models.NewAPIKey(user.ID, models.SDKLoginAPIKeyName, models.AllAPIKeyScopes, &expiresAt)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
+		return
+	}
 
-		if err := h.db.Save(&user).Error; err != nil {
-			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to save API key")
-			return
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Keys from earlier logins keep working on their machines until they
+		// expire; only the expired ones are revoked
+		now := time.Now()
+		err := tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND name = ? AND revoked = ? AND expires_at <= ?", user.ID, models.SDKLoginAPIKeyName, false, now).
+			Updates(map[string]interface{}{"revoked": true, "revoked_at": now}).Error
+		if err != nil {
+			return err
 		}
+		return tx.Create(key).Error
+	})
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to save API key")
+		return
 	}
 
 	// Clear sensitive data
 	user.Password = ""
 
-	apiKey := ""
-	if user.APIKey != nil {
-		apiKey = *user.APIKey
-	}
-
 	h.SuccessResponse(c, SDKLoginResponse{
-		APIKey: apiKey,
+		APIKey: plaintext,
 		User:   user,
 	}, "SDK authentication successful")
 }
 
-// GetCurrentSubscription returns the customer's current active subscription
+// GetCurrentSubscription returns the customer's current subscription
 // @Summary Get current subscription
-// @Description Get the customer's currently active subscription
+// @Description Get the customer's current subscription, or that of an organization they are a member of, flagged when it is in its grace period or suspended
 // @Tags SDK Subscription
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} map[string]interface{}
+// @Success 200 {object} SDKSubscriptionResponse
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /sdk/v1/subscription [get]
@@ -96,7 +146,7 @@ func (h *SDKHandler) GetCurrentSubscription(c *gin.Context) {
 		return
 	}
 
-	subscription, err := customer.GetActiveSubscription(h.db.DB)
+	subscription, err := customer.GetLicensedSubscription(h.db.DB)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
 		return
@@ -105,12 +155,17 @@ func (h *SDKHandler) GetCurrentSubscription(c *gin.Context) {
 	// Load pack information
 	h.db.Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Current subscription retrieved")
+	h.SuccessResponse(c, SDKSubscriptionResponse{
+		Subscription: subscription,
+		Access:       subscription.IsActive(),
+		InGrace:      subscription.IsInGrace(),
+		Suspended:    subscription.IsSuspended(),
+	}, "Current subscription retrieved")
 }
 
 // RequestSubscription allows customer to request a new subscription
 // @Summary Request subscription
-// @Description Request a new subscription for the customer
+// @Description Request a new subscription for the customer, optionally redeeming a coupon code
 // @Tags SDK Subscription
 // @Accept json
 // @Produce json
@@ -134,37 +189,59 @@ func (h *SDKHandler) RequestSubscription(c *gin.Context) {
 		return
 	}
 
-	// Check if customer already has an active subscription
-	if customer.HasActiveSubscription(h.db.DB) {
-		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	subscription, err := h.subscriptions.Request(customer.ID, req.PackSKU, req.CouponCode, h.CurrentActor(c, models.ActorSDK))
+	if err != nil {
+		h.requestError(c, err)
 		return
 	}
 
-	// Verify subscription pack exists
-	var pack models.SubscriptionPack
-	err = h.db.Where("sku = ?", req.PackSKU).First(&pack).Error
+	// Load pack information
+	h.db.Preload("Pack").Preload("Coupon").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription request created successfully")
+}
+
+// StartTrial starts a trial of a pack for the customer
+// @Summary Start trial
+// @Description Start a time-limited trial of a pack right away, without admin approval. Each customer gets one trial per pack; approving the trial converts it to a paid subscription.
+// @Tags SDK Subscription
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body SubscriptionRequest true "Pack to trial"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /sdk/v1/subscription/trial [post]
+func (h *SDKHandler) StartTrial(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
 	if err != nil {
-		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack")
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
 		return
 	}
 
-	// Create subscription request
-	subscription := &models.Subscription{
-		CustomerID:  customer.ID,
-		PackID:      pack.ID,
-		Status:      models.StatusRequested,
-		RequestedAt: time.Now(),
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.CouponCode != "" {
+		h.ErrorResponse(c, http.StatusBadRequest, "Coupons cannot be applied to trials")
+		return
 	}
 
-	if err := h.db.Create(subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create subscription request")
+	subscription, err := h.subscriptions.StartTrial(customer.ID, req.PackSKU, h.CurrentActor(c, models.ActorSDK))
+	if err != nil {
+		h.trialError(c, err)
 		return
 	}
 
 	// Load pack information
 	h.db.Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Subscription request created successfully")
+	h.SuccessResponse(c, subscription, "Trial started successfully")
 }
 
 // DeactivateSubscription allows customer to deactivate their current subscription
@@ -174,7 +251,9 @@ func (h *SDKHandler) RequestSubscription(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param request body TransitionRequest false "Optional reason"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /sdk/v1/subscription/deactivate [put]
@@ -185,26 +264,151 @@ func (h *SDKHandler) DeactivateSubscription(c *gin.Context) {
 		return
 	}
 
+	req, err := bindTransitionRequest(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	subscription, err := h.subscriptions.Deactivate(customer.ID, h.CurrentActor(c, models.ActorSDK), req.Reason)
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to deactivate subscription")
+		return
+	}
+
+	// Load pack information
+	h.db.Preload("Pack").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription deactivated successfully")
+}
+
+// RenewSubscription extends the active subscription by one validity period
+// @Summary Renew subscription
+// @Description Extend the active subscription by the pack's validity, counted from the current expiry date
+// @Tags SDK Subscription
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sdk/v1/subscription/renew [post]
+func (h *SDKHandler) RenewSubscription(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	subscription, err := h.subscriptions.Renew(customer.ID, h.CurrentActor(c, models.ActorSDK))
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusForbidden, "Suspended and trial subscriptions cannot be renewed")
+		return
+	case errors.Is(err, services.ErrPackNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Subscription pack is no longer available")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to renew subscription")
+		return
+	}
+
+	// Load pack information
+	h.db.Preload("Pack").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription renewed successfully")
+}
+
+// ChangePlan moves the active subscription to another pack
+// @Summary Change plan
+// @Description Move the active subscription to another pack, immediately with prorated credit or at the end of the term
+// @Tags SDK Subscription
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ChangePlanRequest true "New pack and when the change takes effect"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /sdk/v1/subscription/change-plan [post]
+func (h *SDKHandler) ChangePlan(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
 	subscription, err := customer.GetActiveSubscription(h.db.DB)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
 		return
 	}
 
-	// Update subscription status
-	subscription.Status = models.StatusInactive
-	now := time.Now()
-	subscription.DeactivatedAt = &now
+	change, err := h.subscriptions.ChangePlan(subscription.ID, req.PackSKU, planChangeEffective(req), h.CurrentActor(c, models.ActorSDK))
+	if err != nil {
+		h.planChangeError(c, err)
+		return
+	}
 
-	if err := h.db.Save(subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to deactivate subscription")
+	h.SuccessResponse(c, change, "Subscription plan changed successfully")
+}
+
+// SetAutoRenew turns automatic renewal of the active subscription on or off
+// @Summary Set auto-renew
+// @Description Enable or disable automatic renewal of the active subscription when it expires
+// @Tags SDK Subscription
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body AutoRenewRequest true "Auto-renew setting"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sdk/v1/subscription/auto-renew [put]
+func (h *SDKHandler) SetAutoRenew(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req AutoRenewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	subscription, err := h.subscriptions.SetAutoRenew(customer.ID, *req.AutoRenew)
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update auto-renew")
 		return
 	}
 
 	// Load pack information
 	h.db.Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Subscription deactivated successfully")
+	h.SuccessResponse(c, subscription, "Auto-renew updated successfully")
 }
 
 // GetSubscriptionHistory returns paginated subscription history for the customer
@@ -267,3 +471,140 @@ func (h *SDKHandler) GetSubscriptionHistory(c *gin.Context) {
 
 	h.PaginatedResponse(c, subscriptions, total, page, limit)
 }
+
+// GetLicense issues a signed license file for the customer's active subscription
+// @Summary Get signed license file
+// @Description Issue an Ed25519-signed license file that clients can verify offline. Members of an organization are licensed through its subscription, and the file carries its organization_id.
+// @Tags SDK Subscription
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /sdk/v1/license [get]
+func (h *SDKHandler) GetLicense(c *gin.Context) {
+	if h.signer == nil {
+		h.ErrorResponse(c, http.StatusServiceUnavailable, "License signing is not configured")
+		return
+	}
+
+	user, err := h.GetCurrentUser(c)
+	if err != nil || user.Customer == nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	subscription, err := user.Customer.GetLicensedSubscription(h.db.DB)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	}
+
+	h.db.Preload("Pack").First(subscription, subscription.ID)
+	if subscription.Pack == nil || subscription.ExpiresAt == nil {
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	}
+	if subscription.IsSuspended() {
+		h.ErrorResponse(c, http.StatusForbidden, "Subscription is suspended")
+		return
+	}
+
+	// Licenses carry the grace deadline so offline checks keep working through it
+	graceEndsAt := subscription.GraceEndsAt
+	if graceEndsAt == nil && !subscription.IsTrial() {
+		graceEndsAt = subscription.Pack.GraceEndsAt(*subscription.ExpiresAt)
+	}
+	if graceEndsAt != nil {
+		utc := graceEndsAt.UTC()
+		graceEndsAt = &utc
+	}
+
+	file, err := h.signer.Sign(license.Claims{
+		SubscriptionID: subscription.ID,
+		CustomerID:     user.Customer.ID,
+		CustomerEmail:  user.Email,
+		PackSKU:        subscription.Pack.SKU,
+		IssuedAt:       time.Now().UTC(),
+		ExpiresAt:      subscription.ExpiresAt.UTC(),
+		GraceEndsAt:    graceEndsAt,
+		OrganizationID: subscription.OrganizationID,
+	})
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to sign license")
+		return
+	}
+
+	h.SuccessResponse(c, file, "License issued successfully")
+}
+
+// GetEntitlements returns the features the customer's current subscription grants
+// @Summary Get entitlements
+// @Description Resolve every catalog feature for the customer's current subscription. Values are booleans, integer limits (-1 is unlimited) or strings; features the pack does not set take their default.
+// @Tags SDK Subscription
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} EntitlementsResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sdk/v1/entitlements [get]
+func (h *SDKHandler) GetEntitlements(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	subscription, entitlements, err := h.entitlements.ForCustomer(customer.ID)
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to resolve entitlements")
+		return
+	}
+	if !subscription.IsActive() {
+		h.ErrorResponse(c, http.StatusForbidden, "Subscription does not currently grant access")
+		return
+	}
+
+	var pack models.SubscriptionPack
+	h.db.Unscoped().Select("sku").First(&pack, subscription.PackID)
+
+	h.SuccessResponse(c, EntitlementsResponse{
+		SubscriptionID: subscription.ID,
+		PackSKU:        pack.SKU,
+		Entitlements:   entitlements,
+	}, "Entitlements retrieved")
+}
+
+// GetLicenseKeys lists the public keys that verify license files
+// @Summary List license verification keys
+// @Description List the current and retired public keys used to verify license files
+// @Tags SDK Authentication
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /sdk/license/keys [get]
+func (h *SDKHandler) GetLicenseKeys(c *gin.Context) {
+	keys := make([]LicenseKeyResponse, 0)
+	if h.verifier != nil {
+		for keyID, key := range h.verifier.Keys() {
+			keys = append(keys, LicenseKeyResponse{
+				KeyID:     keyID,
+				Algorithm: license.Algorithm,
+				PublicKey: base64.StdEncoding.EncodeToString(key),
+				Current:   h.signer != nil && h.signer.KeyID() == keyID,
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+
+	h.SuccessResponse(c, keys, "License keys retrieved")
+}
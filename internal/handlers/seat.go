@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errSeatLimitReached = errors.New("seat limit reached")
+	errSeatTaken        = errors.New("seat is held by another member")
+)
+
+type SeatHandler struct {
+	*BaseHandler
+}
+
+func NewSeatHandler(db *database.DB) *SeatHandler {
+	return &SeatHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// ActivateSeatRequest represents a machine activation request
+type ActivateSeatRequest struct {
+	Fingerprint string `json:"fingerprint" binding:"required,max=255"`
+	Hostname    string `json:"hostname" binding:"max=255"`
+}
+
+// DeactivateSeatRequest represents a machine deactivation request
+type DeactivateSeatRequest struct {
+	Fingerprint string `json:"fingerprint" binding:"required,max=255"`
+}
+
+// ListSeats returns the machines activated on the customer's active subscription
+// @Summary List seats
+// @Description List machines activated on the customer's active subscription. Members of an organization see the machines they activated, its owner and admins see every machine; used counts every machine.
+// @Tags SDK Seats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sdk/v1/seats [get]
+func (h *SeatHandler) ListSeats(c *gin.Context) {
+	customer, subscription, ok := h.activeSubscription(c)
+	if !ok {
+		return
+	}
+
+	var seats []models.Seat
+	err := h.seats(customer, subscription).Order("created_at ASC").Find(&seats).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve seats")
+		return
+	}
+
+	var used int64
+	if err := h.db.Model(&models.Seat{}).Where("subscription_id = ?", subscription.ID).Count(&used).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve seats")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{
+		"seats":     seats,
+		"max_seats": subscription.Pack.MaxSeats,
+		"used":      used,
+	}, "Seats retrieved successfully")
+}
+
+// ActivateSeat activates a machine on the customer's active subscription
+// @Summary Activate seat
+// @Description Activate a machine on the active subscription, or refresh its last-seen time if already active. A machine activated by another member of an organization can only be refreshed by its owner and admins.
+// @Tags SDK Seats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body ActivateSeatRequest true "Machine details"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /sdk/v1/seats/activate [post]
+func (h *SeatHandler) ActivateSeat(c *gin.Context) {
+	var req ActivateSeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	customer, subscription, ok := h.activeSubscription(c)
+	if !ok {
+		return
+	}
+	if subscription.IsSuspended() {
+		h.ErrorResponse(c, http.StatusForbidden, "Subscription is suspended")
+		return
+	}
+	managesAll := h.managesAllSeats(customer, subscription)
+
+	var seat models.Seat
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the subscription row so concurrent activations are counted one at a time
+		var locked models.Subscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, subscription.ID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err := tx.Where("subscription_id = ? AND fingerprint = ?", subscription.ID, req.Fingerprint).First(&seat).Error
+		if err == nil {
+			if seat.CustomerID != customer.ID && !managesAll {
+				return errSeatTaken
+			}
+			seat.LastSeenAt = now
+			if req.Hostname != "" {
+				seat.Hostname = req.Hostname
+			}
+			return tx.Save(&seat).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var used int64
+		if err := tx.Model(&models.Seat{}).Where("subscription_id = ?", subscription.ID).Count(&used).Error; err != nil {
+			return err
+		}
+		if !subscription.Pack.HasSeatAvailable(used) {
+			return errSeatLimitReached
+		}
+
+		seat = models.Seat{
+			SubscriptionID: subscription.ID,
+			CustomerID:     customer.ID,
+			Fingerprint:    req.Fingerprint,
+			Hostname:       req.Hostname,
+			LastSeenAt:     now,
+		}
+		return tx.Create(&seat).Error
+	})
+	if errors.Is(err, errSeatLimitReached) {
+		h.ErrorResponse(c, http.StatusConflict, "Seat limit reached for this subscription")
+		return
+	}
+	if errors.Is(err, errSeatTaken) {
+		h.ErrorResponse(c, http.StatusConflict, "This machine was activated by another member")
+		return
+	}
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to activate seat")
+		return
+	}
+
+	h.SuccessResponse(c, seat, "Seat activated successfully")
+}
+
+// DeactivateSeat releases a machine's seat on the customer's active subscription
+// @Summary Deactivate seat
+// @Description Release the seat held by a machine so it can be used elsewhere. Members of an organization can release the machines they activated, its owner and admins any machine.
+// @Tags SDK Seats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body DeactivateSeatRequest true "Machine fingerprint"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sdk/v1/seats/deactivate [post]
+func (h *SeatHandler) DeactivateSeat(c *gin.Context) {
+	var req DeactivateSeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	customer, subscription, ok := h.activeSubscription(c)
+	if !ok {
+		return
+	}
+
+	result := h.seats(customer, subscription).Where("fingerprint = ?", req.Fingerprint).Delete(&models.Seat{})
+	if result.Error != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to deactivate seat")
+		return
+	}
+	if result.RowsAffected == 0 {
+		h.ErrorResponse(c, http.StatusNotFound, "Seat not found")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Seat deactivated successfully"}, "")
+}
+
+// activeSubscription loads the current customer and their licensed subscription with
+// its pack, writing an error response and returning false when there is none
+func (h *SeatHandler) activeSubscription(c *gin.Context) (*models.Customer, *models.Subscription, bool) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return nil, nil, false
+	}
+
+	subscription, err := customer.GetLicensedSubscription(h.db.DB)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return nil, nil, false
+	}
+
+	if err := h.db.Preload("Pack").First(subscription, subscription.ID).Error; err != nil || subscription.Pack == nil {
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return nil, nil, false
+	}
+
+	return customer, subscription, true
+}
+
+// managesAllSeats checks if the customer manages every seat of the subscription:
+// their own subscription's, or an organization's when they are its owner or an admin
+func (h *SeatHandler) managesAllSeats(customer *models.Customer, subscription *models.Subscription) bool {
+	if subscription.OrganizationID == nil {
+		return subscription.CustomerID == customer.ID
+	}
+
+	var member models.OrganizationMember
+	err := h.db.Where("organization_id = ? AND customer_id = ?", *subscription.OrganizationID, customer.ID).First(&member).Error
+	return err == nil && member.CanManage()
+}
+
+// seats scopes a query to the subscription's seats the customer manages
+func (h *SeatHandler) seats(customer *models.Customer, subscription *models.Subscription) *gorm.DB {
+	query := h.db.Model(&models.Seat{}).Where("subscription_id = ?", subscription.ID)
+	if !h.managesAllSeats(customer, subscription) {
+		query = query.Where("customer_id = ?", customer.ID)
+	}
+	return query
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StaffHandler struct {
+	*BaseHandler
+}
+
+func NewStaffHandler(db *database.DB) *StaffHandler {
+	return &StaffHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// CreateStaffRequest represents the request to create a staff user
+type CreateStaffRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Role     string `json:"role" binding:"required,oneof=super_admin support billing"`
+}
+
+// UpdateStaffRequest represents the request to change a staff user's role
+type UpdateStaffRequest struct {
+	Role string `json:"role" binding:"required,oneof=super_admin support billing"`
+}
+
+// RoleResponse describes a staff role and the permissions it grants
+type RoleResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// ListRoles handles listing the staff roles (admin only)
+// @Summary List staff roles
+// @Description Get every staff role with the permissions it grants
+// @Tags Admin Staff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} RoleResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/roles [get]
+func (h *StaffHandler) ListRoles(c *gin.Context) {
+	roles := make([]RoleResponse, 0, len(models.StaffRoles))
+	for _, role := range models.StaffRoles {
+		roles = append(roles, RoleResponse{Role: role, Permissions: models.RolePermissions[role]})
+	}
+
+	h.SuccessResponse(c, roles, "")
+}
+
+// ListStaff handles listing staff users (admin only)
+// @Summary List staff
+// @Description Get paginated staff users, optionally with one role
+// @Tags Admin Staff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param role query string false "Filter by role"
+// @Param search query string false "Search by email"
+// @Success 200 {object} PaginatedResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/staff [get]
+func (h *StaffHandler) ListStaff(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.User{}).Where("role IN ?", models.StaffRoles)
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if search := c.Query("search"); search != "" {
+		condition, args := database.SearchCondition(search, "email")
+		query = query.Where(condition, args...)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var staff []models.User
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&staff).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve staff")
+		return
+	}
+
+	h.PaginatedResponse(c, staff, total, page, limit)
+}
+
+// CreateStaff handles creating a staff user (admin only)
+// @Summary Create staff user
+// @Description Create a user who signs in to the admin API with the given role
+// @Tags Admin Staff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateStaffRequest true "Staff user"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/admin/staff [post]
+func (h *StaffHandler) CreateStaff(c *gin.Context) {
+	var req CreateStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	// Check if user already exists
+	var existingUser models.User
+	err := h.db.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error
+	if err == nil {
+		h.ErrorResponse(c, http.StatusConflict, "Email already registered")
+		return
+	}
+
+	user := &models.User{
+		Email:    req.Email,
+		Password: req.Password,
+		Role:     req.Role,
+	}
+
+	if err := user.HashPassword(); err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to process password")
+		return
+	}
+
+	if err := h.db.Create(user).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create staff user")
+		return
+	}
+	middleware.SetAuditResourceID(c, user.ID)
+
+	h.SuccessResponse(c, user, "Staff user created successfully")
+}
+
+// GetStaff handles getting a staff user (admin only)
+// @Summary Get staff user
+// @Description Get a staff user by ID
+// @Tags Admin Staff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/staff/{id} [get]
+func (h *StaffHandler) GetStaff(c *gin.Context) {
+	user, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+
+	h.SuccessResponse(c, user, "")
+}
+
+// UpdateStaff handles changing a staff user's role (admin only)
+// @Summary Change staff role
+// @Description Change a staff user's role. Their sessions are revoked so that the new role applies from their next sign-in. Staff cannot change their own role.
+// @Tags Admin Staff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body UpdateStaffRequest true "Role"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/staff/{id} [put]
+func (h *StaffHandler) UpdateStaff(c *gin.Context) {
+	var req UpdateStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	user, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+	if h.isCurrentUser(c, user) {
+		h.ErrorResponse(c, http.StatusBadRequest, "You cannot change your own role")
+		return
+	}
+	middleware.SetAuditSnapshot(c, *user)
+
+	if user.Role != req.Role {
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Update("role", req.Role).Error; err != nil {
+				return err
+			}
+			return revokeSessions(tx, user.ID)
+		})
+		if err != nil {
+			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update staff user")
+			return
+		}
+	}
+
+	h.SuccessResponse(c, user, "Staff role updated successfully")
+}
+
+// DeleteStaff handles removing a staff user (admin only)
+// @Summary Remove staff user
+// @Description Remove a staff user and revoke their sessions. The user is soft deleted so that audit logs and subscription events keep referring to them. Staff cannot remove themselves.
+// @Tags Admin Staff
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/staff/{id} [delete]
+func (h *StaffHandler) DeleteStaff(c *gin.Context) {
+	user, ok := h.findStaff(c)
+	if !ok {
+		return
+	}
+	if h.isCurrentUser(c, user) {
+		h.ErrorResponse(c, http.StatusBadRequest, "You cannot remove yourself")
+		return
+	}
+	middleware.SetAuditSnapshot(c, *user)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := revokeSessions(tx, user.ID); err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove staff user")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Staff user removed successfully"}, "")
+}
+
+// findStaff loads the staff user in the id path parameter, writing an error
+// response and returning false when there is none
+func (h *StaffHandler) findStaff(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID")
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.Where("role IN ?", models.StaffRoles).First(&user, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Staff user not found")
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h *StaffHandler) isCurrentUser(c *gin.Context, user *models.User) bool {
+	userID, _ := c.Get("user_id")
+	id, ok := userID.(uint)
+	return ok && id == user.ID
+}
+
+// revokeSessions logs the user out everywhere
+func revokeSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -1,23 +1,28 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
 	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
 	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type SubscriptionHandler struct {
 	*BaseHandler
+	subscriptions *services.SubscriptionService
 }
 
-func NewSubscriptionHandler(db *database.DB) *SubscriptionHandler {
+func NewSubscriptionHandler(db *database.DB, subscriptions *services.SubscriptionService) *SubscriptionHandler {
 	return &SubscriptionHandler{
-		BaseHandler: NewBaseHandler(db),
+		BaseHandler:   NewBaseHandler(db),
+		subscriptions: subscriptions,
 	}
 }
 
@@ -25,6 +30,7 @@ func NewSubscriptionHandler(db *database.DB) *SubscriptionHandler {
 type CreateSubscriptionRequest struct {
 	CustomerID uint   `json:"customer_id" binding:"required"`
 	PackSKU    string `json:"pack_sku" binding:"required"`
+	CouponCode string `json:"coupon_code"`
 }
 
 
@@ -109,43 +115,23 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 		return
 	}
 
-	// Verify customer exists
-	var customer models.Customer
-	err := h.db.First(&customer, req.CustomerID).Error
-	if err != nil {
+	subscription, err := h.subscriptions.Request(req.CustomerID, req.PackSKU, req.CouponCode, h.CurrentActor(c, models.ActorAdmin))
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
 		h.ErrorResponse(c, http.StatusNotFound, "Customer not found")
 		return
-	}
-
-	// Verify subscription pack exists
-	var pack models.SubscriptionPack
-	err = h.db.Where("sku = ?", req.PackSKU).First(&pack).Error
-	if err != nil {
+	case errors.Is(err, services.ErrPackNotFound):
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription pack not found")
 		return
-	}
-
-	// Check if customer already has an active subscription
-	if customer.HasActiveSubscription(h.db.DB) {
-		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	case err != nil:
+		h.requestError(c, err)
 		return
 	}
 
-	// Create subscription
-	subscription := &models.Subscription{
-		CustomerID:  customer.ID,
-		PackID:      pack.ID,
-		Status:      models.StatusRequested,
-		RequestedAt: time.Now(),
-	}
-
-	if err := h.db.Create(subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create subscription")
-		return
-	}
+	middleware.SetAuditResourceID(c, subscription.ID)
 
 	// Load relationships
-	h.db.Preload("Customer.User").Preload("Pack").First(subscription, subscription.ID)
+	h.db.Preload("Customer.User").Preload("Pack").Preload("Coupon").First(subscription, subscription.ID)
 
 	h.SuccessResponse(c, subscription, "Subscription created successfully")
 }
@@ -180,6 +166,46 @@ func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
 	h.SuccessResponse(c, subscription, "Subscription retrieved successfully")
 }
 
+// GetSubscriptionEvents handles getting the status change timeline of a subscription (admin only)
+// @Summary Get subscription events
+// @Description Get the status change history of a subscription, oldest first
+// @Tags Admin Subscription Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/subscriptions/{id}/events [get]
+func (h *SubscriptionHandler) GetSubscriptionEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var events []models.SubscriptionEvent
+	err = h.db.Preload("Actor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Where("subscription_id = ?", id).Order("created_at ASC, id ASC").Find(&events).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch subscription events")
+		return
+	}
+
+	// Events outlive deleted subscriptions, so only 404 when there is nothing to show
+	if len(events) == 0 {
+		var count int64
+		h.db.Model(&models.Subscription{}).Where("id = ?", id).Count(&count)
+		if count == 0 {
+			h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
+			return
+		}
+	}
+
+	h.SuccessResponse(c, events, "Subscription events retrieved successfully")
+}
+
 // ApproveSubscription handles approving a subscription request (admin only)
 // @Summary Approve subscription
 // @Description Approve a subscription request
@@ -201,31 +227,21 @@ func (h *SubscriptionHandler) ApproveSubscription(c *gin.Context) {
 		return
 	}
 
-	var subscription models.Subscription
-	err = h.db.Preload("Pack").First(&subscription, id).Error
-	if err != nil {
+	subscription, err := h.subscriptions.Approve(uint(id), h.CurrentActor(c, models.ActorAdmin))
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
 		return
-	}
-
-	// Check if subscription can be approved
-	if !subscription.CanTransitionTo(models.StatusApproved) {
+	case errors.Is(err, services.ErrInvalidTransition):
 		h.ErrorResponse(c, http.StatusBadRequest, "Subscription cannot be approved in current status")
 		return
-	}
-
-	// Update subscription status
-	subscription.Status = models.StatusApproved
-	now := time.Now()
-	subscription.ApprovedAt = &now
-
-	if err := h.db.Save(&subscription).Error; err != nil {
+	case err != nil:
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to approve subscription")
 		return
 	}
 
 	// Load relationships
-	h.db.Preload("Customer.User").Preload("Pack").First(&subscription, subscription.ID)
+	h.db.Preload("Customer.User").Preload("Pack").First(subscription, subscription.ID)
 
 	h.SuccessResponse(c, subscription, "Subscription approved successfully")
 }
@@ -251,97 +267,208 @@ func (h *SubscriptionHandler) AssignSubscription(c *gin.Context) {
 		return
 	}
 
-	var subscription models.Subscription
-	err = h.db.Preload("Pack").First(&subscription, id).Error
-	if err != nil {
+	subscription, err := h.subscriptions.Assign(uint(id), h.CurrentActor(c, models.ActorAdmin))
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
 		return
-	}
-
-	// Check if subscription can be assigned
-	if !subscription.CanTransitionTo(models.StatusActive) {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Customer not found")
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
 		h.ErrorResponse(c, http.StatusBadRequest, "Subscription cannot be assigned in current status")
 		return
+	case errors.Is(err, services.ErrActiveSubscriptionExists):
+		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to assign subscription")
+		return
 	}
 
-	// Check if customer already has an active subscription
-	var customer models.Customer
-	err = h.db.First(&customer, subscription.CustomerID).Error
+	// Load relationships
+	h.db.Preload("Customer.User").Preload("Pack").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription assigned successfully")
+}
+
+// UnassignSubscription handles unassigning a current subscription (admin only)
+// @Summary Unassign subscription
+// @Description Unassign an active, in-grace or suspended subscription
+// @Tags Admin Subscription Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Param request body TransitionRequest false "Optional reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/subscriptions/{id}/unassign [put]
+func (h *SubscriptionHandler) UnassignSubscription(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		h.ErrorResponse(c, http.StatusNotFound, "Customer not found")
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
 		return
 	}
 
-	if customer.HasActiveSubscription(h.db.DB) {
-		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	req, err := bindTransitionRequest(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
-	// Update subscription status
-	subscription.Status = models.StatusActive
-	now := time.Now()
-	subscription.AssignedAt = &now
-	subscription.CalculateExpiry(subscription.Pack)
-
-	if err := h.db.Save(&subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to assign subscription")
+	subscription, err := h.subscriptions.Unassign(uint(id), h.CurrentActor(c, models.ActorAdmin), req.Reason)
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusBadRequest, "Only current subscriptions can be unassigned")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to unassign subscription")
 		return
 	}
 
 	// Load relationships
-	h.db.Preload("Customer.User").Preload("Pack").First(&subscription, subscription.ID)
+	h.db.Preload("Customer.User").Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Subscription assigned successfully")
+	h.SuccessResponse(c, subscription, "Subscription unassigned successfully")
 }
 
-// UnassignSubscription handles unassigning an active subscription (admin only)
-// @Summary Unassign subscription
-// @Description Unassign an active subscription
+// SuspendSubscription handles suspending a subscription (admin only)
+// @Summary Suspend subscription
+// @Description Block access for an active or in-grace subscription without ending it, e.g. for non-payment
 // @Tags Admin Subscription Management
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Subscription ID"
+// @Param request body TransitionRequest false "Optional reason"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
-// @Router /api/v1/admin/subscriptions/{id}/unassign [put]
-func (h *SubscriptionHandler) UnassignSubscription(c *gin.Context) {
+// @Router /api/v1/admin/subscriptions/{id}/suspend [put]
+func (h *SubscriptionHandler) SuspendSubscription(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
 		return
 	}
 
-	var subscription models.Subscription
-	err = h.db.First(&subscription, id).Error
+	req, err := bindTransitionRequest(c)
 	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	subscription, err := h.subscriptions.Suspend(uint(id), h.CurrentActor(c, models.ActorAdmin), req.Reason)
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
 		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusBadRequest, "Only active or in-grace subscriptions can be suspended")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to suspend subscription")
+		return
 	}
 
-	// Check if subscription can be unassigned
-	if subscription.Status != models.StatusActive {
-		h.ErrorResponse(c, http.StatusBadRequest, "Only active subscriptions can be unassigned")
+	// Load relationships
+	h.db.Preload("Customer.User").Preload("Pack").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription suspended successfully")
+}
+
+// ResumeSubscription handles lifting a suspension (admin only)
+// @Summary Resume subscription
+// @Description Lift a suspension. The subscription returns to active, to its grace period, or expires if both have passed.
+// @Tags Admin Subscription Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Param request body TransitionRequest false "Optional reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/subscriptions/{id}/resume [put]
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
 		return
 	}
 
-	// Update subscription status
-	subscription.Status = models.StatusInactive
-	now := time.Now()
-	subscription.DeactivatedAt = &now
+	req, err := bindTransitionRequest(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
 
-	if err := h.db.Save(&subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to unassign subscription")
+	subscription, err := h.subscriptions.Resume(uint(id), h.CurrentActor(c, models.ActorAdmin), req.Reason)
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusBadRequest, "Only suspended subscriptions can be resumed")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to resume subscription")
 		return
 	}
 
 	// Load relationships
-	h.db.Preload("Customer.User").Preload("Pack").First(&subscription, subscription.ID)
+	h.db.Preload("Customer.User").Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Subscription unassigned successfully")
+	h.SuccessResponse(c, subscription, "Subscription resumed successfully")
+}
+
+// ChangeSubscriptionPlan handles moving an active subscription to another pack (admin only)
+// @Summary Change subscription plan
+// @Description Move an active subscription to another pack, immediately with prorated credit or at the end of the term
+// @Tags Admin Subscription Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Param request body ChangePlanRequest true "New pack and when the change takes effect"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/admin/subscriptions/{id}/change-plan [post]
+func (h *SubscriptionHandler) ChangeSubscriptionPlan(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription ID")
+		return
+	}
+
+	var req ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	change, err := h.subscriptions.ChangePlan(uint(id), req.PackSKU, planChangeEffective(req), h.CurrentActor(c, models.ActorAdmin))
+	if err != nil {
+		h.planChangeError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, change, "Subscription plan changed successfully")
 }
 
 // DeleteSubscription handles deleting a subscription (admin only)
@@ -370,6 +497,7 @@ func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
 		return
 	}
+	middleware.SetAuditSnapshot(c, subscription)
 
 	if err := h.db.Delete(&subscription).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete subscription")
@@ -412,7 +540,7 @@ func (h *SubscriptionHandler) GetCurrentSubscription(c *gin.Context) {
 
 // RequestSubscription handles customer subscription request
 // @Summary Request subscription
-// @Description Request a new subscription for current customer
+// @Description Request a new subscription for current customer, optionally redeeming a coupon code
 // @Tags Customer Subscription
 // @Accept json
 // @Produce json
@@ -437,37 +565,59 @@ func (h *SubscriptionHandler) RequestSubscription(c *gin.Context) {
 		return
 	}
 
-	// Check if customer already has an active subscription
-	if customer.HasActiveSubscription(h.db.DB) {
-		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	subscription, err := h.subscriptions.Request(customer.ID, req.PackSKU, req.CouponCode, h.CurrentActor(c, models.ActorCustomer))
+	if err != nil {
+		h.requestError(c, err)
 		return
 	}
 
-	// Verify subscription pack exists
-	var pack models.SubscriptionPack
-	err = h.db.Where("sku = ?", req.PackSKU).First(&pack).Error
+	// Load pack information
+	h.db.Preload("Pack").Preload("Coupon").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription request created successfully")
+}
+
+// StartTrial handles starting a trial for the current customer
+// @Summary Start trial
+// @Description Start a time-limited trial of a pack right away, without admin approval. Each customer gets one trial per pack; approving the trial converts it to a paid subscription.
+// @Tags Customer Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SubscriptionRequest true "Pack to trial"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/customer/subscription/trial [post]
+func (h *SubscriptionHandler) StartTrial(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
 	if err != nil {
-		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack")
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
 		return
 	}
 
-	// Create subscription request
-	subscription := &models.Subscription{
-		CustomerID:  customer.ID,
-		PackID:      pack.ID,
-		Status:      models.StatusRequested,
-		RequestedAt: time.Now(),
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.CouponCode != "" {
+		h.ErrorResponse(c, http.StatusBadRequest, "Coupons cannot be applied to trials")
+		return
 	}
 
-	if err := h.db.Create(subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create subscription request")
+	subscription, err := h.subscriptions.StartTrial(customer.ID, req.PackSKU, h.CurrentActor(c, models.ActorCustomer))
+	if err != nil {
+		h.trialError(c, err)
 		return
 	}
 
 	// Load pack information
 	h.db.Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Subscription request created successfully")
+	h.SuccessResponse(c, subscription, "Trial started successfully")
 }
 
 // DeactivateSubscription handles customer subscription deactivation
@@ -477,7 +627,9 @@ func (h *SubscriptionHandler) RequestSubscription(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param request body TransitionRequest false "Optional reason"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -489,26 +641,154 @@ func (h *SubscriptionHandler) DeactivateSubscription(c *gin.Context) {
 		return
 	}
 
+	req, err := bindTransitionRequest(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	subscription, err := h.subscriptions.Deactivate(customer.ID, h.CurrentActor(c, models.ActorCustomer), req.Reason)
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to deactivate subscription")
+		return
+	}
+
+	// Load pack information
+	h.db.Preload("Pack").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription deactivated successfully")
+}
+
+// RenewSubscription extends the active subscription by one validity period
+// @Summary Renew subscription
+// @Description Extend the active subscription by the pack's validity, counted from the current expiry date
+// @Tags Customer Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/subscription/renew [post]
+func (h *SubscriptionHandler) RenewSubscription(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	subscription, err := h.subscriptions.Renew(customer.ID, h.CurrentActor(c, models.ActorCustomer))
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusForbidden, "Suspended and trial subscriptions cannot be renewed")
+		return
+	case errors.Is(err, services.ErrPackNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Subscription pack is no longer available")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to renew subscription")
+		return
+	}
+
+	// Load pack information
+	h.db.Preload("Pack").First(subscription, subscription.ID)
+
+	h.SuccessResponse(c, subscription, "Subscription renewed successfully")
+}
+
+// ChangePlan moves the active subscription to another pack
+// @Summary Change plan
+// @Description Move the active subscription to another pack, immediately with prorated credit or at the end of the term
+// @Tags Customer Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePlanRequest true "New pack and when the change takes effect"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/customer/subscription/change-plan [post]
+func (h *SubscriptionHandler) ChangePlan(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req ChangePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
 	subscription, err := customer.GetActiveSubscription(h.db.DB)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
 		return
 	}
 
-	// Update subscription status
-	subscription.Status = models.StatusInactive
-	now := time.Now()
-	subscription.DeactivatedAt = &now
+	change, err := h.subscriptions.ChangePlan(subscription.ID, req.PackSKU, planChangeEffective(req), h.CurrentActor(c, models.ActorCustomer))
+	if err != nil {
+		h.planChangeError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, change, "Subscription plan changed successfully")
+}
 
-	if err := h.db.Save(subscription).Error; err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to deactivate subscription")
+// SetAutoRenew turns automatic renewal of the active subscription on or off
+// @Summary Set auto-renew
+// @Description Enable or disable automatic renewal of the active subscription when it expires
+// @Tags Customer Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AutoRenewRequest true "Auto-renew setting"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/customer/subscription/auto-renew [put]
+func (h *SubscriptionHandler) SetAutoRenew(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req AutoRenewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	subscription, err := h.subscriptions.SetAutoRenew(customer.ID, *req.AutoRenew)
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+		return
+	case err != nil:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update auto-renew")
 		return
 	}
 
 	// Load pack information
 	h.db.Preload("Pack").First(subscription, subscription.ID)
 
-	h.SuccessResponse(c, subscription, "Subscription deactivated successfully")
+	h.SuccessResponse(c, subscription, "Auto-renew updated successfully")
 }
 
 // GetSubscriptionHistory handles getting customer's subscription history
@@ -572,3 +852,69 @@ func (h *SubscriptionHandler) GetSubscriptionHistory(c *gin.Context) {
 
 	h.PaginatedResponse(c, subscriptions, total, page, limit)
 }
+
+// planChangeEffective defaults an unset effective field to an immediate change
+func planChangeEffective(req ChangePlanRequest) string {
+	if req.Effective == "" {
+		return services.ChangeImmediately
+	}
+	return req.Effective
+}
+
+// requestError writes the response for a subscription request that failed
+func (h *BaseHandler) requestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrActiveSubscriptionExists):
+		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	case errors.Is(err, services.ErrPackNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack")
+	case errors.Is(err, services.ErrCouponNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid coupon code")
+	case errors.Is(err, services.ErrCouponNotValid):
+		h.ErrorResponse(c, http.StatusBadRequest, "This coupon is not valid at this time")
+	case errors.Is(err, services.ErrCouponNotApplicable):
+		h.ErrorResponse(c, http.StatusBadRequest, "This coupon does not apply to the subscription pack")
+	case errors.Is(err, services.ErrCouponExhausted):
+		h.ErrorResponse(c, http.StatusConflict, "This coupon has reached its redemption limit")
+	case errors.Is(err, services.ErrCouponLimitReached):
+		h.ErrorResponse(c, http.StatusConflict, "This coupon has already been redeemed by the customer")
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create subscription request")
+	}
+}
+
+// trialError writes the response for a trial that could not be started
+func (h *BaseHandler) trialError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPackNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack")
+	case errors.Is(err, services.ErrTrialNotOffered):
+		h.ErrorResponse(c, http.StatusBadRequest, "This subscription pack does not offer a trial")
+	case errors.Is(err, services.ErrTrialUsed):
+		h.ErrorResponse(c, http.StatusConflict, "A trial of this subscription pack has already been used")
+	case errors.Is(err, services.ErrActiveSubscriptionExists):
+		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to start trial")
+	}
+}
+
+// planChangeError writes the response for a failed plan change
+func (h *BaseHandler) planChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		h.ErrorResponse(c, http.StatusNotFound, "Subscription not found")
+	case errors.Is(err, services.ErrInvalidTransition):
+		h.ErrorResponse(c, http.StatusBadRequest, "Only active or in-grace subscriptions can change plan")
+	case errors.Is(err, services.ErrPackNotFound):
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack")
+	case errors.Is(err, services.ErrSamePack):
+		h.ErrorResponse(c, http.StatusBadRequest, "Subscription is already on this pack")
+	case errors.Is(err, services.ErrPlanChangePending):
+		h.ErrorResponse(c, http.StatusConflict, "A plan change is already scheduled for this subscription")
+	case errors.Is(err, services.ErrActiveSubscriptionExists):
+		h.ErrorResponse(c, http.StatusConflict, "Customer already has an active subscription")
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to change subscription plan")
+	}
+}
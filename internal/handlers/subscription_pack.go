@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
 	"cursor-ai-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type SubscriptionPackHandler struct {
@@ -22,19 +26,50 @@ func NewSubscriptionPackHandler(db *database.DB) *SubscriptionPackHandler {
 
 // CreatePackRequest represents the subscription pack creation request
 type CreatePackRequest struct {
-	Name           string  `json:"name" binding:"required"`
-	Description    string  `json:"description"`
-	SKU            string  `json:"sku" binding:"required"`
-	Price          float64 `json:"price" binding:"required,min=0"`
-	ValidityMonths int     `json:"validity_months" binding:"required,min=1,max=12"`
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	SKU             string `json:"sku" binding:"required"`
+	Currency        string `json:"currency"`
+	UnitAmount      *int64 `json:"unit_amount" binding:"required,min=0"`
+	ValidityMonths  int    `json:"validity_months" binding:"required,min=1,max=12"`
+	MaxSeats        int    `json:"max_seats" binding:"omitempty,min=1"`
+	GracePeriodDays int    `json:"grace_period_days" binding:"min=0,max=90"`
+	TrialEnabled    bool   `json:"trial_enabled"`
+	TrialDays       int    `json:"trial_days" binding:"min=0,max=90"`
 }
 
 // UpdatePackRequest represents the subscription pack update request
 type UpdatePackRequest struct {
-	Name           string  `json:"name"`
-	Description    string  `json:"description"`
-	Price          float64 `json:"price" binding:"min=0"`
-	ValidityMonths int     `json:"validity_months" binding:"min=1,max=12"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Currency        string `json:"currency"`
+	UnitAmount      *int64 `json:"unit_amount" binding:"omitempty,min=0"`
+	ValidityMonths  int    `json:"validity_months" binding:"min=1,max=12"`
+	MaxSeats        int    `json:"max_seats" binding:"omitempty,min=1"`
+	GracePeriodDays *int   `json:"grace_period_days" binding:"omitempty,min=0,max=90"`
+	TrialEnabled    *bool  `json:"trial_enabled"`
+	TrialDays       *int   `json:"trial_days" binding:"omitempty,min=0,max=90"`
+}
+
+// SetPackPriceRequest sets a pack's price in a currency, optionally for one region only
+type SetPackPriceRequest struct {
+	Currency   string `json:"currency" binding:"required"`
+	Region     string `json:"region"`
+	UnitAmount *int64 `json:"unit_amount" binding:"required,min=0"`
+}
+
+// PackOffer is a pack as offered to a customer, with the price they would pay
+type PackOffer struct {
+	ID              uint         `json:"id"`
+	Name            string       `json:"name"`
+	Description     string       `json:"description"`
+	SKU             string       `json:"sku"`
+	ValidityMonths  int          `json:"validity_months"`
+	MaxSeats        int          `json:"max_seats"`
+	GracePeriodDays int          `json:"grace_period_days"`
+	TrialEnabled    bool         `json:"trial_enabled"`
+	TrialDays       int          `json:"trial_days"`
+	Price           models.Price `json:"price"`
 }
 
 // ListPacks handles listing all subscription packs (admin only)
@@ -71,8 +106,8 @@ func (h *SubscriptionPackHandler) ListPacks(c *gin.Context) {
 
 	// Apply search filter
 	if search != "" {
-		query = query.Where("name ILIKE ? OR description ILIKE ? OR sku ILIKE ?", 
-			"%"+search+"%", "%"+search+"%", "%"+search+"%")
+		condition, args := database.SearchCondition(search, "name", "description", "sku")
+		query = query.Where(condition, args...)
 	}
 
 	// Get total count
@@ -81,7 +116,7 @@ func (h *SubscriptionPackHandler) ListPacks(c *gin.Context) {
 
 	// Get packs
 	var packs []models.SubscriptionPack
-	err := query.Offset(offset).Limit(limit).Find(&packs).Error
+	err := query.Preload("Prices").Offset(offset).Limit(limit).Find(&packs).Error
 	if err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve subscription packs")
 		return
@@ -119,19 +154,39 @@ func (h *SubscriptionPackHandler) CreatePack(c *gin.Context) {
 		return
 	}
 
+	maxSeats := req.MaxSeats
+	if maxSeats == 0 {
+		maxSeats = 1
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	if !models.IsValidCurrency(currency) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code")
+		return
+	}
+
 	// Create subscription pack
 	pack := &models.SubscriptionPack{
-		Name:           req.Name,
-		Description:    req.Description,
-		SKU:            req.SKU,
-		Price:          req.Price,
-		ValidityMonths: req.ValidityMonths,
+		Name:            req.Name,
+		Description:     req.Description,
+		SKU:             req.SKU,
+		Currency:        currency,
+		UnitAmount:      *req.UnitAmount,
+		ValidityMonths:  req.ValidityMonths,
+		MaxSeats:        maxSeats,
+		GracePeriodDays: req.GracePeriodDays,
+		TrialEnabled:    req.TrialEnabled,
+		TrialDays:       req.TrialDays,
 	}
 
 	if err := h.db.Create(pack).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create subscription pack")
 		return
 	}
+	middleware.SetAuditResourceID(c, pack.ID)
 
 	h.SuccessResponse(c, pack, "Subscription pack created successfully")
 }
@@ -157,7 +212,7 @@ func (h *SubscriptionPackHandler) GetPack(c *gin.Context) {
 	}
 
 	var pack models.SubscriptionPack
-	err = h.db.Preload("Subscriptions.Customer").First(&pack, id).Error
+	err = h.db.Preload("Prices").Preload("Subscriptions.Customer").First(&pack, id).Error
 	if err != nil {
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription pack not found")
 		return
@@ -200,6 +255,7 @@ func (h *SubscriptionPackHandler) UpdatePack(c *gin.Context) {
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription pack not found")
 		return
 	}
+	middleware.SetAuditSnapshot(c, pack)
 
 	// Update fields
 	if req.Name != "" {
@@ -208,12 +264,38 @@ func (h *SubscriptionPackHandler) UpdatePack(c *gin.Context) {
 	if req.Description != "" {
 		pack.Description = req.Description
 	}
-	if req.Price > 0 {
-		pack.Price = req.Price
+	if req.Currency != "" {
+		currency := strings.ToUpper(req.Currency)
+		if !models.IsValidCurrency(currency) {
+			h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code")
+			return
+		}
+		var overlapping int64
+		h.db.Model(&models.PackPrice{}).Where("pack_id = ? AND currency = ? AND region = ?", pack.ID, currency, "").Count(&overlapping)
+		if overlapping > 0 {
+			h.ErrorResponse(c, http.StatusConflict, "The pack already has a separate price in this currency; remove it first")
+			return
+		}
+		pack.Currency = currency
+	}
+	if req.UnitAmount != nil {
+		pack.UnitAmount = *req.UnitAmount
 	}
 	if req.ValidityMonths > 0 {
 		pack.ValidityMonths = req.ValidityMonths
 	}
+	if req.MaxSeats > 0 {
+		pack.MaxSeats = req.MaxSeats
+	}
+	if req.GracePeriodDays != nil {
+		pack.GracePeriodDays = *req.GracePeriodDays
+	}
+	if req.TrialEnabled != nil {
+		pack.TrialEnabled = *req.TrialEnabled
+	}
+	if req.TrialDays != nil {
+		pack.TrialDays = *req.TrialDays
+	}
 
 	if err := h.db.Save(&pack).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update subscription pack")
@@ -249,6 +331,7 @@ func (h *SubscriptionPackHandler) DeletePack(c *gin.Context) {
 		h.ErrorResponse(c, http.StatusNotFound, "Subscription pack not found")
 		return
 	}
+	middleware.SetAuditSnapshot(c, pack)
 
 	if err := h.db.Delete(&pack).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete subscription pack")
@@ -257,3 +340,195 @@ func (h *SubscriptionPackHandler) DeletePack(c *gin.Context) {
 
 	h.SuccessResponse(c, gin.H{"message": "Subscription pack deleted successfully"}, "")
 }
+
+// ListPackPrices handles listing a pack's currency and regional prices (admin only)
+// @Summary List pack prices
+// @Description Get the prices of a pack in other currencies and its regional overrides
+// @Tags Admin Subscription Pack Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription Pack ID"
+// @Success 200 {array} models.PackPrice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/packs/{id}/prices [get]
+func (h *SubscriptionPackHandler) ListPackPrices(c *gin.Context) {
+	pack, ok := h.loadPack(c)
+	if !ok {
+		return
+	}
+
+	prices := make([]models.PackPrice, 0)
+	if err := h.db.Where("pack_id = ?", pack.ID).Order("currency ASC, region ASC").Find(&prices).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve pack prices")
+		return
+	}
+
+	h.SuccessResponse(c, prices, "")
+}
+
+// SetPackPrice handles setting a pack's price in a currency or region (admin only)
+// @Summary Set pack price
+// @Description Set the pack's price, in minor units, in another currency, or override it for one region (ISO 3166 alpha-2 country code). Replaces an existing price for the same currency and region.
+// @Tags Admin Subscription Pack Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription Pack ID"
+// @Param request body SetPackPriceRequest true "Price"
+// @Success 200 {object} models.PackPrice
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/packs/{id}/prices [put]
+func (h *SubscriptionPackHandler) SetPackPrice(c *gin.Context) {
+	pack, ok := h.loadPack(c)
+	if !ok {
+		return
+	}
+
+	var req SetPackPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	region := strings.ToUpper(req.Region)
+	if !models.IsValidCurrency(currency) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code")
+		return
+	}
+	if region != "" && !models.IsValidRegion(region) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Region must be an ISO 3166 alpha-2 country code")
+		return
+	}
+	if currency == pack.Currency && region == "" {
+		h.ErrorResponse(c, http.StatusBadRequest, "This is the pack's own currency; update the pack's unit_amount instead")
+		return
+	}
+
+	price := models.PackPrice{PackID: pack.ID, Currency: currency, Region: region}
+	err := h.db.Where(&price, "PackID", "Currency", "Region").First(&price).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to set pack price")
+		return
+	}
+	middleware.SetAuditSnapshot(c, price)
+
+	price.UnitAmount = *req.UnitAmount
+	if err := h.db.Save(&price).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to set pack price")
+		return
+	}
+
+	h.SuccessResponse(c, price, "Pack price set successfully")
+}
+
+// RemovePackPrice handles removing one of a pack's prices (admin only)
+// @Summary Remove pack price
+// @Description Remove a currency price or regional override from a pack
+// @Tags Admin Subscription Pack Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription Pack ID"
+// @Param price_id path int true "Pack price ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/packs/{id}/prices/{price_id} [delete]
+func (h *SubscriptionPackHandler) RemovePackPrice(c *gin.Context) {
+	pack, ok := h.loadPack(c)
+	if !ok {
+		return
+	}
+
+	priceID, err := strconv.ParseUint(c.Param("price_id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid pack price ID")
+		return
+	}
+
+	var price models.PackPrice
+	if err := h.db.Where("pack_id = ?", pack.ID).First(&price, priceID).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Pack price not found")
+		return
+	}
+	middleware.SetAuditSnapshot(c, price)
+
+	if err := h.db.Delete(&price).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove pack price")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Pack price removed successfully"}, "")
+}
+
+// ListOffers handles listing the packs on sale with the current customer's prices
+// @Summary List packs for sale
+// @Description Get every pack on sale with the price the current customer would pay, based on their currency preference and country
+// @Tags Customer Subscription
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {array} PackOffer
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/customer/packs [get]
+// @Router /sdk/v1/packs [get]
+func (h *SubscriptionPackHandler) ListOffers(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var packs []models.SubscriptionPack
+	if err := h.db.Preload("Prices").Order("id ASC").Find(&packs).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve subscription packs")
+		return
+	}
+
+	offers := make([]PackOffer, 0, len(packs))
+	for i := range packs {
+		pack := &packs[i]
+		offers = append(offers, PackOffer{
+			ID:              pack.ID,
+			Name:            pack.Name,
+			Description:     pack.Description,
+			SKU:             pack.SKU,
+			ValidityMonths:  pack.ValidityMonths,
+			MaxSeats:        pack.MaxSeats,
+			GracePeriodDays: pack.GracePeriodDays,
+			TrialEnabled:    pack.OffersTrial(),
+			TrialDays:       pack.TrialDays,
+			Price:           pack.PriceFor(customer.Currency, customer.Country),
+		})
+	}
+
+	h.SuccessResponse(c, offers, "")
+}
+
+// loadPack loads the pack named by the id parameter, writing the error
+// response and returning false if it cannot
+func (h *SubscriptionPackHandler) loadPack(c *gin.Context) (*models.SubscriptionPack, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription pack ID")
+		return nil, false
+	}
+
+	var pack models.SubscriptionPack
+	if err := h.db.First(&pack, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Subscription pack not found")
+		return nil, false
+	}
+	return &pack, true
+}
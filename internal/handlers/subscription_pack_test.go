@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/database/databasetest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPackCreateAndSearch(t *testing.T) {
+	databasetest.Drivers(t, databasetest.SQLite, func(t *testing.T, db *database.DB) {
+		handler := NewSubscriptionPackHandler(db)
+		router := gin.New()
+		router.POST("/packs", handler.CreatePack)
+		router.GET("/packs", handler.ListPacks)
+
+		amount := int64(1999)
+		mustCreate(t, router, "/packs",
+			CreatePackRequest{Name: "Pro Monthly", SKU: "PRO-1", UnitAmount: &amount, ValidityMonths: 1},
+			CreatePackRequest{Name: "Team", Description: "100% of the features for teams", SKU: "TEAM", UnitAmount: &amount, ValidityMonths: 12, MaxSeats: 10},
+		)
+		code, _ := perform(t, router, http.MethodPost, "/packs", CreatePackRequest{
+			Name: "Pro Again", SKU: "PRO-1", UnitAmount: &amount, ValidityMonths: 1,
+		})
+		if code != http.StatusConflict {
+			t.Fatalf("duplicate SKU: status %d, want %d", code, http.StatusConflict)
+		}
+
+		checkSearch(t, router, "/packs", "sku", []searchCase{
+			{"", []string{"PRO-1", "TEAM"}},
+			{"pro", []string{"PRO-1"}},
+			{"FEATURES", []string{"TEAM"}},
+			{"%", []string{"TEAM"}},
+			{"_", nil},
+		})
+	})
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UsageHandler struct {
+	*BaseHandler
+	usage *services.UsageService
+}
+
+func NewUsageHandler(db *database.DB, usage *services.UsageService) *UsageHandler {
+	return &UsageHandler{
+		BaseHandler: NewBaseHandler(db),
+		usage:       usage,
+	}
+}
+
+// ReportUsageRequest represents a usage report. The idempotency key may also
+// be sent in the Idempotency-Key header.
+type ReportUsageRequest struct {
+	Metric         string `json:"metric" binding:"required"`
+	Quantity       int64  `json:"quantity" binding:"omitempty,min=1"`
+	IdempotencyKey string `json:"idempotency_key" binding:"max=255"`
+}
+
+// UsageSummary is the total usage of one metric by one customer
+type UsageSummary struct {
+	CustomerID uint   `json:"customer_id"`
+	Metric     string `json:"metric"`
+	Quantity   int64  `json:"quantity"`
+	Periods    int64  `json:"periods"`
+}
+
+// ReportUsage records metered usage for the customer's current subscription
+// @Summary Report usage
+// @Description Record usage of a metered limit feature for the current billing period. Reports that would exceed the pack's limit are rejected; a repeated idempotency key is acknowledged without being counted again.
+// @Tags SDK Usage
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Idempotency-Key header string false "Idempotency key, if not given in the body"
+// @Param request body ReportUsageRequest true "Usage report"
+// @Success 200 {object} services.UsageRecord
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /sdk/v1/usage [post]
+func (h *UsageHandler) ReportUsage(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	var req ReportUsageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	}
+	if req.IdempotencyKey == "" || len(req.IdempotencyKey) > 255 {
+		h.ErrorResponse(c, http.StatusBadRequest, "An idempotency key of up to 255 characters is required")
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	record, err := h.usage.Record(customer.ID, req.Metric, req.Quantity, req.IdempotencyKey, time.Now())
+	if err != nil {
+		h.usageError(c, err)
+		return
+	}
+
+	message := "Usage recorded"
+	if record.Duplicate {
+		message = "Usage already recorded"
+	}
+	h.SuccessResponse(c, record, message)
+}
+
+// GetQuota checks the customer's remaining quota for a metric
+// @Summary Check quota
+// @Description Report the limit, usage and remaining quota of a metric for the current billing period, and whether quantity more is allowed
+// @Tags SDK Usage
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param metric query string true "Limit feature key, e.g. api.calls"
+// @Param quantity query int false "Amount about to be used" default(1)
+// @Success 200 {object} services.Quota
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /sdk/v1/quota [get]
+func (h *UsageHandler) GetQuota(c *gin.Context) {
+	customer, err := h.GetCurrentCustomer(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Customer not found")
+		return
+	}
+
+	metric := c.Query("metric")
+	if metric == "" {
+		h.ErrorResponse(c, http.StatusBadRequest, "metric is required")
+		return
+	}
+	quantity, err := strconv.ParseInt(c.DefaultQuery("quantity", "1"), 10, 64)
+	if err != nil || quantity < 0 {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid quantity")
+		return
+	}
+
+	quota, err := h.usage.Check(customer.ID, metric, quantity, time.Now())
+	if err != nil {
+		h.usageError(c, err)
+		return
+	}
+
+	h.SuccessResponse(c, quota, "Quota retrieved")
+}
+
+// ListUsage handles listing usage per subscription and billing period (admin only)
+// @Summary List usage
+// @Description Get paginated usage counters, one per customer subscription, metric and billing period, newest period first
+// @Tags Admin Usage
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param customer_id query int false "Filter by customer ID"
+// @Param metric query string false "Filter by metric"
+// @Param from query string false "Only periods starting at or after this time (RFC 3339)"
+// @Param to query string false "Only periods starting before this time (RFC 3339)"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/usage [get]
+func (h *UsageHandler) ListUsage(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query, err := h.filteredQuery(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var counters []models.UsageCounter
+	err = query.Preload("Customer.User").Order("period_start DESC, id DESC").Offset(offset).Limit(limit).Find(&counters).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve usage")
+		return
+	}
+
+	h.PaginatedResponse(c, counters, total, page, limit)
+}
+
+// GetUsageSummary handles reporting total usage per customer and metric (admin only)
+// @Summary Usage summary
+// @Description Total usage per customer and metric over the billing periods that match the filters
+// @Tags Admin Usage
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param customer_id query int false "Filter by customer ID"
+// @Param metric query string false "Filter by metric"
+// @Param from query string false "Only periods starting at or after this time (RFC 3339)"
+// @Param to query string false "Only periods starting before this time (RFC 3339)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/usage/summary [get]
+func (h *UsageHandler) GetUsageSummary(c *gin.Context) {
+	query, err := h.filteredQuery(c)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary := make([]UsageSummary, 0)
+	err = query.
+		Select("customer_id, metric, SUM(quantity) AS quantity, COUNT(*) AS periods").
+		Group("customer_id, metric").
+		Order("customer_id ASC, metric ASC").
+		Scan(&summary).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to summarize usage")
+		return
+	}
+
+	h.SuccessResponse(c, summary, "")
+}
+
+// filteredQuery builds the usage counter query from the shared filter parameters
+func (h *UsageHandler) filteredQuery(c *gin.Context) (*gorm.DB, error) {
+	query := h.db.Model(&models.UsageCounter{})
+
+	if customerID := c.Query("customer_id"); customerID != "" {
+		id, err := strconv.ParseUint(customerID, 10, 32)
+		if err != nil {
+			return nil, errors.New("Invalid customer_id")
+		}
+		query = query.Where("customer_id = ?", id)
+	}
+	if metric := c.Query("metric"); metric != "" {
+		query = query.Where("metric = ?", metric)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, errors.New("Invalid from time, expected RFC 3339")
+		}
+		query = query.Where("period_start >= ?", t.UTC())
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, errors.New("Invalid to time, expected RFC 3339")
+		}
+		query = query.Where("period_start < ?", t.UTC())
+	}
+
+	return query, nil
+}
+
+// usageError writes the response for a failed usage report or quota check
+func (h *UsageHandler) usageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNoActiveSubscription):
+		h.ErrorResponse(c, http.StatusNotFound, "No active subscription found")
+	case errors.Is(err, services.ErrNoAccess):
+		h.ErrorResponse(c, http.StatusForbidden, "Subscription does not currently grant access")
+	case errors.Is(err, services.ErrUnknownMetric):
+		h.ErrorResponse(c, http.StatusBadRequest, "Unknown metric; metrics must be limit features")
+	case errors.Is(err, services.ErrQuotaExceeded):
+		h.ErrorResponse(c, http.StatusTooManyRequests, "Usage quota exceeded for this billing period")
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		h.ErrorResponse(c, http.StatusConflict, "Idempotency key was already used for a different usage report")
+	default:
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to process usage")
+	}
+}
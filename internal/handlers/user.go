@@ -4,20 +4,23 @@ import (
 	"net/http"
 	"time"
 
+	"cursor-ai-backend/internal/auth"
 	"cursor-ai-backend/internal/database"
 	"cursor-ai-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
 	*BaseHandler
+	tokens *auth.TokenManager
 }
 
-func NewUserHandler(db *database.DB) *UserHandler {
+func NewUserHandler(db *database.DB, tokens *auth.TokenManager) *UserHandler {
 	return &UserHandler{
 		BaseHandler: NewBaseHandler(db),
+		tokens:      tokens,
 	}
 }
 
@@ -33,12 +36,26 @@ type SignupRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
 	Phone    string `json:"phone"`
+	Currency string `json:"currency"`
+	Country  string `json:"country"`
+}
+
+// RefreshRequest represents the token refresh request structure
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse represents an access token and its rotating refresh token
+type TokenResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
 }
 
 // LoginResponse represents the login response structure
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	TokenResponse
+	User models.User `json:"user"`
 }
 
 // SDKLoginResponse represents the SDK login response structure
@@ -66,7 +83,7 @@ func (h *UserHandler) AdminLogin(c *gin.Context) {
 	}
 
 	var user models.User
-	err := h.db.Where("email = ? AND role = ?", req.Email, "admin").First(&user).Error
+	err := h.db.Where("email = ? AND role IN ?", req.Email, models.StaffRoles).First(&user).Error
 	if err != nil {
 		h.ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
 		return
@@ -77,7 +94,7 @@ func (h *UserHandler) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := h.generateJWT(&user)
+	tokens, err := h.startSession(c, &user)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
 		return
@@ -85,11 +102,10 @@ func (h *UserHandler) AdminLogin(c *gin.Context) {
 
 	// Clear sensitive data
 	user.Password = ""
-	user.APIKey = nil
 
 	h.SuccessResponse(c, LoginResponse{
-		Token: token,
-		User:  user,
+		TokenResponse: *tokens,
+		User:          user,
 	}, "Login successful")
 }
 
@@ -112,7 +128,7 @@ func (h *UserHandler) CustomerLogin(c *gin.Context) {
 	}
 
 	var user models.User
-	err := h.db.Preload("Customer").Where("email = ? AND role = ?", req.Email, "customer").First(&user).Error
+	err := h.db.Preload("Customer").Where("email = ? AND role = ?", req.Email, models.RoleCustomer).First(&user).Error
 	if err != nil {
 		h.ErrorResponse(c, http.StatusUnauthorized, "Invalid credentials")
 		return
@@ -123,7 +139,7 @@ func (h *UserHandler) CustomerLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := h.generateJWT(&user)
+	tokens, err := h.startSession(c, &user)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
 		return
@@ -131,11 +147,10 @@ func (h *UserHandler) CustomerLogin(c *gin.Context) {
 
 	// Clear sensitive data
 	user.Password = ""
-	user.APIKey = nil
 
 	h.SuccessResponse(c, LoginResponse{
-		Token: token,
-		User:  user,
+		TokenResponse: *tokens,
+		User:          user,
 	}, "Login successful")
 }
 
@@ -157,9 +172,15 @@ func (h *UserHandler) CustomerSignup(c *gin.Context) {
 		return
 	}
 
+	var preferences models.Customer
+	if !setPricingPreferences(&preferences, &req.Currency, &req.Country) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Currency must be an ISO 4217 code and country an ISO 3166 alpha-2 code")
+		return
+	}
+
 	// Check if user already exists
 	var existingUser models.User
-	err := h.db.Where("email = ?", req.Email).First(&existingUser).Error
+	err := h.db.Unscoped().Where("email = ?", req.Email).First(&existingUser).Error
 	if err == nil {
 		h.ErrorResponse(c, http.StatusConflict, "Email already registered")
 		return
@@ -169,7 +190,7 @@ func (h *UserHandler) CustomerSignup(c *gin.Context) {
 	user := &models.User{
 		Email:    req.Email,
 		Password: req.Password,
-		Role:     "customer",
+		Role:     models.RoleCustomer,
 	}
 
 	if err := user.HashPassword(); err != nil {
@@ -177,12 +198,6 @@ func (h *UserHandler) CustomerSignup(c *gin.Context) {
 		return
 	}
 
-	// Generate API key for customer
-	if err := user.GenerateAPIKey(); err != nil {
-		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate API key")
-		return
-	}
-
 	if err := h.db.Create(user).Error; err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user")
 		return
@@ -190,9 +205,11 @@ func (h *UserHandler) CustomerSignup(c *gin.Context) {
 
 	// Create customer profile
 	customer := &models.Customer{
-		UserID: user.ID,
-		Name:   req.Name,
-		Phone:  req.Phone,
+		UserID:   user.ID,
+		Name:     req.Name,
+		Phone:    req.Phone,
+		Currency: preferences.Currency,
+		Country:  preferences.Country,
 	}
 
 	if err := h.db.Create(customer).Error; err != nil {
@@ -201,7 +218,7 @@ func (h *UserHandler) CustomerSignup(c *gin.Context) {
 	}
 
 	// Generate JWT token
-	token, err := h.generateJWT(user)
+	tokens, err := h.startSession(c, user)
 	if err != nil {
 		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
 		return
@@ -212,24 +229,162 @@ func (h *UserHandler) CustomerSignup(c *gin.Context) {
 
 	// Clear sensitive data
 	user.Password = ""
-	user.APIKey = nil
 
 	h.SuccessResponse(c, LoginResponse{
-		Token: token,
-		User:  *user,
+		TokenResponse: *tokens,
+		User:          *user,
 	}, "Registration successful")
 }
 
-// generateJWT creates a JWT token for the user
-func (h *UserHandler) generateJWT(user *models.User) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24 hours
-		"iat":     time.Now().Unix(),
+// RefreshToken exchanges a refresh token for a new access token
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated and the old one stops working.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/auth/refresh [post]
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	var session models.Session
+	err := h.db.Preload("User").Where("refresh_token_hash = ?", auth.HashToken(req.RefreshToken)).First(&session).Error
+	if err != nil || !session.IsValid() || session.User == nil {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	// Rotate the refresh token, guarding on the old hash so it can only be used once
+	now := time.Now()
+	result := h.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, session.RefreshTokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": refreshHash,
+			"last_used_at":       now,
+			"expires_at":         now.Add(h.tokens.RefreshTTL()),
+		})
+	if result.Error != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh session")
+		return
+	}
+	if result.RowsAffected == 0 {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	token, expiresAt, err := h.tokens.Generate(session.User, session.ID)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	h.SuccessResponse(c, TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, "Token refreshed successfully")
+}
+
+// Logout revokes the current session
+// @Summary Logout
+// @Description Revoke the current session and its refresh token
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/auth/logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	err := h.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Logged out successfully"}, "")
+}
+
+// LogoutAll revokes every session of the current user
+// @Summary Logout all sessions
+// @Description Revoke every session of the current user on all devices
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/auth/logout-all [post]
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		h.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	result := h.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"revoked_sessions": result.RowsAffected}, "Logged out of all sessions")
+}
+
+// startSession creates a session for the user and issues its access and refresh tokens
+func (h *UserHandler) startSession(c *gin.Context, user *models.User) (*TokenResponse, error) {
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
+		ExpiresAt:        time.Now().Add(h.tokens.RefreshTTL()),
+	}
+
+	var token string
+	var expiresAt time.Time
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		token, expiresAt, err = h.tokens.Generate(user, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte("your-secret-key-change-in-production")) // TODO: Use config
+	return &TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
 }
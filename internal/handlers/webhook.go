@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/middleware"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	*BaseHandler
+}
+
+func NewWebhookHandler(db *database.DB) *WebhookHandler {
+	return &WebhookHandler{
+		BaseHandler: NewBaseHandler(db),
+	}
+}
+
+// CreateWebhookRequest represents a request to register a webhook endpoint.
+// A secret is generated when none is given.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`
+	Secret      string   `json:"secret"`
+}
+
+// UpdateWebhookRequest represents a request to update a webhook endpoint
+type UpdateWebhookRequest struct {
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      *[]string `json:"events"`
+	Active      *bool     `json:"active"`
+}
+
+// WebhookSecretResponse is returned when an endpoint is created; the secret
+// is not shown again
+type WebhookSecretResponse struct {
+	models.WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// ListWebhooks handles listing webhook endpoints (admin only)
+// @Summary List webhook endpoints
+// @Description Get all registered webhook endpoints
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	var endpoints []models.WebhookEndpoint
+	if err := h.db.Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve webhook endpoints")
+		return
+	}
+
+	h.SuccessResponse(c, endpoints, "")
+}
+
+// CreateWebhook handles registering a webhook endpoint (admin only)
+// @Summary Create webhook endpoint
+// @Description Register a URL to receive subscription events. The signing secret is only returned in this response.
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateWebhookRequest true "Webhook endpoint"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/v1/admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if !isValidWebhookURL(req.URL) {
+		h.ErrorResponse(c, http.StatusBadRequest, "URL must be an absolute http or https URL")
+		return
+	}
+	if !validWebhookEvents(req.Events) {
+		h.ErrorResponse(c, http.StatusBadRequest, "Unknown webhook event")
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = webhooks.GenerateSecret()
+		if err != nil {
+			h.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate webhook secret")
+			return
+		}
+	}
+
+	endpoint := models.WebhookEndpoint{
+		URL:         req.URL,
+		Description: req.Description,
+		Secret:      secret,
+		Events:      req.Events,
+		Active:      true,
+	}
+	if err := h.db.Create(&endpoint).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to create webhook endpoint")
+		return
+	}
+	middleware.SetAuditResourceID(c, endpoint.ID)
+
+	h.SuccessResponse(c, WebhookSecretResponse{WebhookEndpoint: endpoint, Secret: secret}, "Webhook endpoint created successfully")
+}
+
+// GetWebhook handles getting a webhook endpoint (admin only)
+// @Summary Get webhook endpoint
+// @Description Get webhook endpoint details by ID
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	endpoint, ok := h.findEndpoint(c)
+	if !ok {
+		return
+	}
+
+	h.SuccessResponse(c, endpoint, "")
+}
+
+// UpdateWebhook handles updating a webhook endpoint (admin only)
+// @Summary Update webhook endpoint
+// @Description Change the URL, description, event filter or active flag of a webhook endpoint
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Param request body UpdateWebhookRequest true "Fields to update"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	endpoint, ok := h.findEndpoint(c)
+	if !ok {
+		return
+	}
+	middleware.SetAuditSnapshot(c, *endpoint)
+
+	if req.URL != "" {
+		if !isValidWebhookURL(req.URL) {
+			h.ErrorResponse(c, http.StatusBadRequest, "URL must be an absolute http or https URL")
+			return
+		}
+		endpoint.URL = req.URL
+	}
+	if req.Description != "" {
+		endpoint.Description = req.Description
+	}
+	if req.Events != nil {
+		if !validWebhookEvents(*req.Events) {
+			h.ErrorResponse(c, http.StatusBadRequest, "Unknown webhook event")
+			return
+		}
+		endpoint.Events = *req.Events
+	}
+	if req.Active != nil {
+		endpoint.Active = *req.Active
+	}
+
+	if err := h.db.Save(endpoint).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to update webhook endpoint")
+		return
+	}
+
+	h.SuccessResponse(c, endpoint, "Webhook endpoint updated successfully")
+}
+
+// DeleteWebhook handles removing a webhook endpoint and its delivery log (admin only)
+// @Summary Delete webhook endpoint
+// @Description Delete a webhook endpoint together with its deliveries
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	endpoint, ok := h.findEndpoint(c)
+	if !ok {
+		return
+	}
+	middleware.SetAuditSnapshot(c, *endpoint)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", endpoint.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(endpoint).Error
+	})
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete webhook endpoint")
+		return
+	}
+
+	h.SuccessResponse(c, gin.H{"message": "Webhook endpoint deleted successfully"}, "")
+}
+
+// ListDeliveries handles listing the delivery log of a webhook endpoint (admin only)
+// @Summary List webhook deliveries
+// @Description Get paginated deliveries for a webhook endpoint, newest first
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Param status query string false "Filter by status (pending, delivered, failed)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} PaginatedResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	endpoint, ok := h.findEndpoint(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	h.PaginatedResponse(c, deliveries, total, page, limit)
+}
+
+// RedeliverDelivery handles queueing a delivery to be sent again (admin only)
+// @Summary Redeliver webhook
+// @Description Queue a new delivery with the same payload as an earlier one
+// @Tags Admin Webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook endpoint ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	endpoint, ok := h.findEndpoint(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid delivery ID")
+		return
+	}
+
+	var original models.WebhookDelivery
+	err = h.db.Where("id = ? AND endpoint_id = ?", deliveryID, endpoint.ID).First(&original).Error
+	if err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Delivery not found")
+		return
+	}
+
+	// A fresh row keeps the log of the original attempts intact
+	delivery := models.WebhookDelivery{
+		EndpointID:    endpoint.ID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := h.db.Create(&delivery).Error; err != nil {
+		h.ErrorResponse(c, http.StatusInternalServerError, "Failed to queue redelivery")
+		return
+	}
+
+	h.SuccessResponse(c, delivery, "Delivery queued for redelivery")
+}
+
+// findEndpoint loads the endpoint named by the :id parameter, writing the
+// error response itself when it cannot
+func (h *WebhookHandler) findEndpoint(c *gin.Context) (*models.WebhookEndpoint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook endpoint ID")
+		return nil, false
+	}
+
+	var endpoint models.WebhookEndpoint
+	if err := h.db.First(&endpoint, id).Error; err != nil {
+		h.ErrorResponse(c, http.StatusNotFound, "Webhook endpoint not found")
+		return nil, false
+	}
+	return &endpoint, true
+}
+
+func isValidWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validWebhookEvents(events []string) bool {
+	for _, e := range events {
+		if !models.IsValidWebhookEvent(e) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditSnapshotKey   = "audit_snapshot"
+	auditResourceIDKey = "audit_resource_id"
+	auditRedacted      = "[REDACTED]"
+)
+
+// auditSensitiveFields are never written to the audit log in clear text
+var auditSensitiveFields = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"refresh_token": true,
+	"api_key":       true,
+	"secret":        true,
+}
+
+// SetAuditSnapshot stores the state of the target resource before a handler
+// changes it, so the audit entry can record what each field was changed from
+func SetAuditSnapshot(c *gin.Context, resource interface{}) {
+	c.Set(auditSnapshotKey, resource)
+}
+
+// SetAuditResourceID records the id of the resource a handler created, for
+// routes that do not carry an :id parameter
+func SetAuditResourceID(c *gin.Context, id uint) {
+	c.Set(auditResourceIDKey, fmt.Sprint(id))
+}
+
+// AuditLog middleware writes an audit entry for every mutating request in the group.
+// It must run after JWTAuth so the admin is known.
+func AuditLog(db *database.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		c.Next()
+
+		entry := models.AuditLog{
+			AdminID:    c.GetUint("user_id"),
+			AdminEmail: c.GetString("user_email"),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			ResourceID: c.Param("id"),
+			StatusCode: c.Writer.Status(),
+			Success:    c.Writer.Status() < http.StatusBadRequest,
+			IPAddress:  c.ClientIP(),
+		}
+		if id := c.GetString(auditResourceIDKey); id != "" {
+			entry.ResourceID = id
+		}
+		snapshot, _ := c.Get(auditSnapshotKey)
+		entry.Changes = auditDiff(snapshot, body, c.Request.Method == http.MethodDelete)
+
+		if err := db.Create(&entry).Error; err != nil {
+			log.Printf("Failed to write audit log for %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}
+}
+
+// auditDiff compares the request body with the snapshot taken before the change.
+// Only fields present in the body are recorded; on delete every scalar field of
+// the snapshot is recorded as removed.
+func auditDiff(snapshot interface{}, body []byte, deleted bool) map[string]models.AuditChange {
+	before := map[string]interface{}{}
+	if snapshot != nil {
+		if raw, err := json.Marshal(snapshot); err == nil {
+			json.Unmarshal(raw, &before)
+		}
+	}
+
+	changes := map[string]models.AuditChange{}
+	if deleted {
+		for field, value := range before {
+			switch value.(type) {
+			case nil, map[string]interface{}, []interface{}:
+				continue
+			}
+			changes[field] = redactChange(field, models.AuditChange{From: value})
+		}
+		return changes
+	}
+
+	after := map[string]interface{}{}
+	if len(body) > 0 {
+		json.Unmarshal(body, &after)
+	}
+	for field, value := range after {
+		from, existed := before[field]
+		if existed && reflect.DeepEqual(from, value) {
+			continue
+		}
+		changes[field] = redactChange(field, models.AuditChange{From: from, To: value})
+	}
+	return changes
+}
+
+func redactChange(field string, change models.AuditChange) models.AuditChange {
+	if !auditSensitiveFields[field] {
+		return change
+	}
+	if change.From != nil {
+		change.From = auditRedacted
+	}
+	if change.To != nil {
+		change.To = auditRedacted
+	}
+	return change
+}
@@ -3,24 +3,17 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"cursor-ai-backend/internal/auth"
 	"cursor-ai-backend/internal/database"
 	"cursor-ai-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWT Claims structure
-type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
-}
-
 // JWTAuth middleware for JWT authentication
-func JWTAuth() gin.HandlerFunc {
+func JWTAuth(tokens *auth.TokenManager, db *database.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -38,19 +31,18 @@ func JWTAuth() gin.HandlerFunc {
 		}
 
 		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte("your-secret-key-change-in-production"), nil // TODO: Use config
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := tokens.Parse(tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		// Reject tokens whose session has been logged out
+		var session models.Session
+		err = db.First(&session, claims.SessionID).Error
+		if err != nil || session.UserID != claims.UserID || session.IsRevoked() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
 			c.Abort()
 			return
 		}
@@ -59,15 +51,16 @@ func JWTAuth() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("session_id", session.ID)
 		c.Next()
 	}
 }
 
-// AdminOnly middleware to restrict access to admin users only
-func AdminOnly() gin.HandlerFunc {
+// StaffOnly middleware to restrict access to users with a staff role
+func StaffOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
-		if !exists || role != "admin" {
+		role, _ := c.Get("user_role")
+		if name, ok := role.(string); !ok || !models.IsStaffRole(name) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
@@ -76,11 +69,25 @@ func AdminOnly() gin.HandlerFunc {
 	}
 }
 
+// RequirePermission middleware to restrict an admin route to staff whose role grants the given permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("user_role")
+		if name, ok := role.(string); ok && models.RoleHasPermission(name, permission) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing required permission: " + permission})
+		c.Abort()
+	}
+}
+
 // CustomerOnly middleware to restrict access to customer users only
 func CustomerOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("user_role")
-		if !exists || role != "customer" {
+		if !exists || role != models.RoleCustomer {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Customer access required"})
 			c.Abort()
 			return
@@ -110,26 +117,50 @@ func APIKeyAuth() gin.HandlerFunc {
 		
 		db := dbInterface.(*database.DB)
 		
-		// Find user by API key
-		var user models.User
-		err := db.Where("api_key = ?", apiKey).First(&user).Error
-		if err != nil {
+		// Find key by hash
+		var key models.APIKey
+		err := db.Preload("User").Where("key_hash = ?", models.HashAPIKey(apiKey)).First(&key).Error
+		if err != nil || key.User == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 			c.Abort()
 			return
 		}
 
-		// Check if user has a valid API key
-		if !user.HasAPIKey() {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		// Check if the key has been revoked or has expired
+		if !key.IsUsable() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key has been revoked or has expired"})
 			c.Abort()
 			return
 		}
 
+		db.Model(&key).UpdateColumn("last_used_at", time.Now())
+
+		user := key.User
+
 		// Set user info in context
 		c.Set("user_id", user.ID)
 		c.Set("user_email", user.Email)
 		c.Set("user_role", user.Role)
+		c.Set("api_key_id", key.ID)
+		c.Set("api_key_scopes", key.Scopes)
 		c.Next()
 	}
 }
+
+// RequireScope middleware to restrict an SDK route to API keys granted the given scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Get("api_key_scopes")
+		granted, _ := scopes.([]string)
+
+		for _, s := range granted {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing required scope: " + scope})
+		c.Abort()
+	}
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeSubscriptionRead       = "subscription:read"
+	ScopeSubscriptionRequest    = "subscription:request"
+	ScopeSubscriptionDeactivate = "subscription:deactivate"
+	ScopeLicenseRead            = "license:read"
+	ScopeSeatRead               = "seat:read"
+	ScopeSeatWrite              = "seat:write"
+	ScopeUsageWrite             = "usage:write"
+)
+
+// AllAPIKeyScopes lists every scope an API key can carry
+var AllAPIKeyScopes = []string{
+	ScopeSubscriptionRead,
+	ScopeSubscriptionRequest,
+	ScopeSubscriptionDeactivate,
+	ScopeLicenseRead,
+	ScopeSeatRead,
+	ScopeSeatWrite,
+	ScopeUsageWrite,
+}
+
+// SDKLoginAPIKeyName names the keys issued by SDK login. Every login issues
+// another key, so that each machine has its own, and each expires on its own.
+const SDKLoginAPIKeyName = "SDK login"
+
+// APIKeyPrefixLength is how much of the key is kept in clear text for identification
+const APIKeyPrefixLength = 15
+
+// APIKey is a named SDK credential. Only the SHA-256 hash of the key is stored;
+// the full key is shown to the customer once, when it is created.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Revoked    bool       `json:"revoked" gorm:"not null;default:false"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// NewAPIKey generates a new key for the user and returns the model together
+// with the plaintext key, which is not recoverable afterwards
+func NewAPIKey(userID uint, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", err
+	}
+	key := "sk-sdk-" + hex.EncodeToString(bytes)
+
+	return &APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:APIKeyPrefixLength],
+		KeyHash:   HashAPIKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, key, nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of a plaintext key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsValidAPIKeyScope checks if scope is a known API key scope
+func IsValidAPIKeyScope(scope string) bool {
+	for _, known := range AllAPIKeyScopes {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUsable checks if the key is neither revoked nor expired
+func (k *APIKey) IsUsable() bool {
+	if k.Revoked {
+		return false
+	}
+	return k.ExpiresAt == nil || k.ExpiresAt.After(time.Now())
+}
+
+// HasScope checks if the key was granted the given scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditChange is the before and after value of one field touched by an admin action
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditLog records one mutating request made through the admin API
+type AuditLog struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	AdminID    uint                   `json:"admin_id" gorm:"not null;index"`
+	AdminEmail string                 `json:"admin_email"`
+	Method     string                 `json:"method" gorm:"not null"`
+	Route      string                 `json:"route" gorm:"not null"`
+	Path       string                 `json:"path"`
+	ResourceID string                 `json:"resource_id" gorm:"index"`
+	Changes    map[string]AuditChange `json:"changes" gorm:"serializer:json"`
+	StatusCode int                    `json:"status_code"`
+	Success    bool                   `json:"success"`
+	IPAddress  string                 `json:"ip_address"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"index"`
+}
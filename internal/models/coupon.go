@@ -0,0 +1,91 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Coupon discount types
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// Coupon is a discount code a customer can give when requesting a
+// subscription. The discount comes off the first billed period. Limits of 0
+// mean unlimited, and an empty PackSKUs list applies to every pack.
+type Coupon struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Code           string         `json:"code" gorm:"uniqueIndex;not null"`
+	Description    string         `json:"description"`
+	DiscountType   string         `json:"discount_type" gorm:"not null"`
+	PercentOff     int            `json:"percent_off"` // percent coupons, 1-100
+	AmountOff      int64          `json:"amount_off"`  // fixed coupons, in minor units of Currency
+	Currency       string         `json:"currency"`    // fixed coupons only
+	ValidFrom      *time.Time     `json:"valid_from"`
+	ValidUntil     *time.Time     `json:"valid_until"`
+	MaxRedemptions int            `json:"max_redemptions" gorm:"not null;default:0"`
+	MaxPerCustomer int            `json:"max_per_customer" gorm:"not null;default:0"`
+	TimesRedeemed  int            `json:"times_redeemed" gorm:"not null;default:0"`
+	PackSKUs       []string       `json:"pack_skus" gorm:"column:pack_skus;serializer:json"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// NormalizeCouponCode returns the stored form of a coupon code as typed by a
+// customer. Codes are case-insensitive.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// IsValidCouponCode checks a normalized coupon code
+func IsValidCouponCode(code string) bool {
+	return couponCodePattern.MatchString(code)
+}
+
+// IsValidAt checks if the coupon's validity window includes t
+func (c *Coupon) IsValidAt(t time.Time) bool {
+	if c.ValidFrom != nil && t.Before(*c.ValidFrom) {
+		return false
+	}
+	return c.ValidUntil == nil || t.Before(*c.ValidUntil)
+}
+
+// IsExhausted checks if the coupon has been redeemed as often as allowed
+func (c *Coupon) IsExhausted() bool {
+	return c.MaxRedemptions > 0 && c.TimesRedeemed >= c.MaxRedemptions
+}
+
+// AppliesTo checks if the coupon can be used for the pack SKU
+func (c *Coupon) AppliesTo(sku string) bool {
+	if len(c.PackSKUs) == 0 {
+		return true
+	}
+	for _, s := range c.PackSKUs {
+		if s == sku {
+			return true
+		}
+	}
+	return false
+}
+
+// Discount returns the discount on a price in minor units of currency, never
+// more than the price. Fixed coupons only apply to prices in their currency.
+func (c *Coupon) Discount(amount int64, currency string) (int64, bool) {
+	switch c.DiscountType {
+	case DiscountPercent:
+		return (amount*int64(c.PercentOff) + 50) / 100, true
+	case DiscountFixed:
+		if c.Currency != currency {
+			return 0, false
+		}
+		return min(c.AmountOff, amount), true
+	}
+	return 0, false
+}
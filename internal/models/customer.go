@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,6 +12,8 @@ type Customer struct {
 	UserID    uint           `json:"user_id" gorm:"uniqueIndex;not null"`
 	Name      string         `json:"name" gorm:"not null"`
 	Phone     string         `json:"phone"`
+	Currency  string         `json:"currency"` // preferred ISO 4217 currency, empty for the pack's own
+	Country   string         `json:"country"`  // ISO 3166 country code for regional prices
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
@@ -20,19 +23,39 @@ type Customer struct {
 	Subscriptions []*Subscription `json:"subscriptions,omitempty" gorm:"foreignKey:CustomerID"`
 }
 
-// GetActiveSubscription returns the currently active subscription for this customer
+// GetActiveSubscription returns the customer's own current subscription, which
+// may be active, a trial, in its grace period or suspended. Subscriptions of
+// organizations billed to the customer are not theirs.
 func (c *Customer) GetActiveSubscription(db *gorm.DB) (*Subscription, error) {
 	var subscription Subscription
-	err := db.Where("customer_id = ? AND status = ?", c.ID, "active").First(&subscription).Error
+	err := db.Where("customer_id = ? AND organization_id IS NULL AND status IN ?", c.ID, CurrentStatuses).First(&subscription).Error
 	if err != nil {
 		return nil, err
 	}
 	return &subscription, nil
 }
 
-// HasActiveSubscription checks if customer has an active subscription
+// GetLicensedSubscription returns the subscription the customer is licensed
+// through: their own current subscription or, when they have none, the
+// current subscription of an organization they are a member of
+func (c *Customer) GetLicensedSubscription(db *gorm.DB) (*Subscription, error) {
+	subscription, err := c.GetActiveSubscription(db)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return subscription, err
+	}
+
+	var shared Subscription
+	organizations := db.Model(&OrganizationMember{}).Select("organization_id").Where("customer_id = ?", c.ID)
+	err = db.Where("organization_id IN (?) AND status IN ?", organizations, CurrentStatuses).Order("id ASC").First(&shared).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shared, nil
+}
+
+// HasActiveSubscription checks if customer has a current subscription of their own
 func (c *Customer) HasActiveSubscription(db *gorm.DB) bool {
 	var count int64
-	db.Model(&Subscription{}).Where("customer_id = ? AND status = ?", c.ID, "active").Count(&count)
+	db.Model(&Subscription{}).Where("customer_id = ? AND organization_id IS NULL AND status IN ?", c.ID, CurrentStatuses).Count(&count)
 	return count > 0
 }
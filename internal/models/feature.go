@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Feature value types
+const (
+	FeatureTypeBoolean = "boolean"
+	FeatureTypeLimit   = "limit"
+	FeatureTypeString  = "string"
+)
+
+// UnlimitedValue is the limit value that means no limit
+const UnlimitedValue int64 = -1
+
+var featureKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+
+// ErrInvalidFeatureValue is returned when a value does not match the feature type
+var ErrInvalidFeatureValue = errors.New("value does not match the feature type")
+
+// Feature is an entry in the entitlement catalog. Packs grant a value for it
+// through PackFeature; customers whose pack does not list it get DefaultValue.
+type Feature struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Key          string    `json:"key" gorm:"uniqueIndex;not null"`
+	Name         string    `json:"name" gorm:"not null"`
+	Description  string    `json:"description"`
+	Type         string    `json:"type" gorm:"not null"`
+	DefaultValue string    `json:"default_value"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PackFeature is the value of a feature granted by a subscription pack
+type PackFeature struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PackID    uint      `json:"pack_id" gorm:"not null"`
+	FeatureID uint      `json:"feature_id" gorm:"not null"`
+	Value     string    `json:"value" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	Feature *Feature `json:"feature,omitempty" gorm:"foreignKey:FeatureID"`
+}
+
+// Entitlement is a feature resolved for a customer, with its value typed
+// according to the feature: bool, int64 or string
+type Entitlement struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// IsValidFeatureType checks if t is a known feature type
+func IsValidFeatureType(t string) bool {
+	switch t {
+	case FeatureTypeBoolean, FeatureTypeLimit, FeatureTypeString:
+		return true
+	}
+	return false
+}
+
+// IsValidFeatureKey checks that key is a lowercase identifier such as "api.calls"
+func IsValidFeatureKey(key string) bool {
+	return featureKeyPattern.MatchString(key)
+}
+
+// ParseValue converts a stored value to the feature's type. An empty value is
+// the zero value of the type: false, 0 or "".
+func (f *Feature) ParseValue(raw string) (interface{}, error) {
+	switch f.Type {
+	case FeatureTypeBoolean:
+		if raw == "" {
+			return false, nil
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, ErrInvalidFeatureValue
+		}
+		return v, nil
+	case FeatureTypeLimit:
+		if raw == "" {
+			return int64(0), nil
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < UnlimitedValue {
+			return nil, ErrInvalidFeatureValue
+		}
+		return v, nil
+	case FeatureTypeString:
+		return raw, nil
+	}
+	return nil, ErrInvalidFeatureValue
+}
+
+// Entitlement resolves the feature with the given stored value
+func (f *Feature) Entitlement(raw string) Entitlement {
+	value, err := f.ParseValue(raw)
+	if err != nil {
+		// Values are validated on write, so fall back to the default rather than fail
+		value, _ = f.ParseValue(f.DefaultValue)
+	}
+	return Entitlement{Key: f.Key, Type: f.Type, Value: value}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type InvoiceStatus string
+
+const (
+	InvoiceDraft InvoiceStatus = "draft"
+	InvoiceOpen  InvoiceStatus = "open"
+	InvoicePaid  InvoiceStatus = "paid"
+	InvoiceVoid  InvoiceStatus = "void"
+)
+
+// Why an invoice was issued
+const (
+	BillingSubscriptionCreate  = "subscription_create"
+	BillingSubscriptionRenewal = "subscription_renewal"
+	BillingPlanChange          = "plan_change"
+)
+
+// Invoice bills a customer for one subscription period. Amounts are in minor
+// currency units (cents) so that totals add up exactly.
+type Invoice struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	Number         string        `json:"number" gorm:"uniqueIndex;not null"`
+	CustomerID     uint          `json:"customer_id" gorm:"not null;index"`
+	SubscriptionID uint          `json:"subscription_id" gorm:"not null;index"`
+	Status         InvoiceStatus `json:"status" gorm:"not null"`
+	BillingReason  string        `json:"billing_reason" gorm:"not null"`
+	Currency       string        `json:"currency" gorm:"not null"`
+	Subtotal       int64         `json:"subtotal" gorm:"not null"`
+	TaxRate        float64       `json:"tax_rate" gorm:"type:decimal(5,2);not null;default:0"`
+	TaxAmount      int64         `json:"tax_amount" gorm:"not null"`
+	Total          int64         `json:"total" gorm:"not null"`
+	PeriodStart    *time.Time    `json:"period_start"`
+	PeriodEnd      *time.Time    `json:"period_end"`
+	IssuedAt       *time.Time    `json:"issued_at"`
+	DueAt          *time.Time    `json:"due_at"`
+	PaidAt         *time.Time    `json:"paid_at"`
+	VoidedAt       *time.Time    `json:"voided_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+
+	// Relationships
+	Customer *Customer     `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Lines    []InvoiceLine `json:"lines,omitempty" gorm:"foreignKey:InvoiceID"`
+}
+
+// InvoiceLine is one line item. Credits have a negative amount.
+type InvoiceLine struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	InvoiceID   uint      `json:"invoice_id" gorm:"not null;index"`
+	Description string    `json:"description" gorm:"not null"`
+	Quantity    int64     `json:"quantity" gorm:"not null;default:1"`
+	UnitAmount  int64     `json:"unit_amount" gorm:"not null"`
+	Amount      int64     `json:"amount" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// InvoiceSequence holds the last issued invoice number. It is incremented in
+// the transaction that creates the invoice, so numbers have no gaps.
+type InvoiceSequence struct {
+	ID         uint  `gorm:"primaryKey"`
+	LastNumber int64 `gorm:"not null"`
+}
+
+// CanTransitionTo checks if the invoice can move to the given status
+func (i *Invoice) CanTransitionTo(newStatus InvoiceStatus) bool {
+	switch i.Status {
+	case InvoiceDraft:
+		return newStatus == InvoiceOpen || newStatus == InvoiceVoid
+	case InvoiceOpen:
+		return newStatus == InvoicePaid || newStatus == InvoiceVoid
+	}
+	return false
+}
+
+// FormatInvoiceNumber renders a sequence number as an invoice number
+func FormatInvoiceNumber(n int64) string {
+	return fmt.Sprintf("INV-%06d", n)
+}
+
+// FormatAmount renders an amount in minor units with the currency's decimal
+// places, e.g. "12.50 USD", "1250 JPY" or "1.250 KWD"
+func FormatAmount(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	exponent := CurrencyExponent(currency)
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d %s", sign, amount, currency)
+	}
+	unit := int64(1)
+	for i := 0; i < exponent; i++ {
+		unit *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, exponent, amount%unit, currency)
+}
@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{1250, "USD", "12.50 USD"},
+		{5, "EUR", "0.05 EUR"},
+		{-1250, "USD", "-12.50 USD"},
+		{1250, "JPY", "1250 JPY"},
+		{-500, "KRW", "-500 KRW"},
+		{1250, "KWD", "1.250 KWD"},
+		{7, "BHD", "0.007 BHD"},
+		{12345, "CLF", "1.2345 CLF"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatAmount(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("FormatAmount(%d, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
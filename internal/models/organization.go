@@ -0,0 +1,96 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization member roles
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// InvitationValidity is how long an organization invitation can be accepted
+const InvitationValidity = 7 * 24 * time.Hour
+
+// Organization lets several customers share subscriptions. The organization
+// owns its subscriptions, which are billed to the owner's customer account
+// (CustomerID), and every member is licensed through them.
+type Organization struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Name       string         `json:"name" gorm:"not null"`
+	CustomerID uint           `json:"customer_id" gorm:"not null;index"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// Relationships
+	Customer *Customer            `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Members  []OrganizationMember `json:"members,omitempty" gorm:"foreignKey:OrganizationID"`
+}
+
+// OrganizationMember is a customer's membership of an organization
+type OrganizationMember struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID uint      `json:"organization_id" gorm:"not null;uniqueIndex:idx_organization_members_org_customer"`
+	CustomerID     uint      `json:"customer_id" gorm:"not null;uniqueIndex:idx_organization_members_org_customer;index"`
+	Role           string    `json:"role" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relationships
+	Organization *Organization `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
+	Customer     *Customer     `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
+
+// OrganizationInvitation invites an email address to join an organization.
+// Only the SHA-256 hash of the token is stored; the token itself is handed
+// out once, when the invitation is created.
+type OrganizationInvitation struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID uint       `json:"organization_id" gorm:"not null;index"`
+	Email          string     `json:"email" gorm:"not null"`
+	Role           string     `json:"role" gorm:"not null"`
+	TokenHash      string     `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedByID    uint       `json:"invited_by_id" gorm:"not null"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Relationships
+	Organization *Organization `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
+}
+
+// HasCurrentSubscription checks if the organization has a current subscription
+func (o *Organization) HasCurrentSubscription(db *gorm.DB) bool {
+	var count int64
+	db.Model(&Subscription{}).Where("organization_id = ? AND status IN ?", o.ID, CurrentStatuses).Count(&count)
+	return count > 0
+}
+
+// CanManage checks if the member may invite, remove and change the role of
+// other members
+func (m *OrganizationMember) CanManage() bool {
+	return m.Role == OrgRoleOwner || m.Role == OrgRoleAdmin
+}
+
+// IsPending checks if the invitation can still be accepted at t
+func (i *OrganizationInvitation) IsPending(t time.Time) bool {
+	return i.AcceptedAt == nil && t.Before(i.ExpiresAt)
+}
+
+// NewInvitationToken generates an invitation token and returns it with the
+// hash to store
+func NewInvitationToken() (string, string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token := "inv_" + hex.EncodeToString(bytes)
+	return token, HashAPIKey(token), nil
+}
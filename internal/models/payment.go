@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type PaymentStatus string
+
+const (
+	PaymentPending   PaymentStatus = "pending"
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentExpired   PaymentStatus = "expired"
+)
+
+// Payment is a checkout session started with a payment provider for a
+// requested subscription. Amount is in minor currency units.
+type Payment struct {
+	ID             uint          `json:"id" gorm:"primaryKey"`
+	Provider       string        `json:"provider" gorm:"not null;uniqueIndex:idx_payments_session"`
+	SessionID      string        `json:"session_id" gorm:"not null;uniqueIndex:idx_payments_session"`
+	CheckoutURL    string        `json:"checkout_url"`
+	CustomerID     uint          `json:"customer_id" gorm:"not null;index"`
+	SubscriptionID uint          `json:"subscription_id" gorm:"not null;index"`
+	InvoiceID      *uint         `json:"invoice_id"`
+	Status         PaymentStatus `json:"status" gorm:"not null"`
+	Amount         int64         `json:"amount" gorm:"not null"`
+	Currency       string        `json:"currency" gorm:"not null"`
+	EventID        string        `json:"event_id"`
+	FailureReason  string        `json:"failure_reason"`
+	PaidAt         *time.Time    `json:"paid_at"`
+	ExpiresAt      *time.Time    `json:"expires_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+
+	// Relationships
+	Customer *Customer `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
@@ -0,0 +1,110 @@
+package models
+
+// User roles. Every role but customer is a staff role that signs in to the
+// admin API.
+const (
+	RoleCustomer   = "customer"
+	RoleSuperAdmin = "super_admin"
+	RoleSupport    = "support"
+	RoleBilling    = "billing"
+)
+
+// StaffRoles lists the roles that can sign in to the admin API
+var StaffRoles = []string{RoleSuperAdmin, RoleSupport, RoleBilling}
+
+// Staff permissions
+const (
+	PermissionCustomersRead        = "customers:read"
+	PermissionCustomersWrite       = "customers:write"
+	PermissionPacksRead            = "packs:read"
+	PermissionPacksWrite           = "packs:write"
+	PermissionFeaturesRead         = "features:read"
+	PermissionFeaturesWrite        = "features:write"
+	PermissionCouponsRead          = "coupons:read"
+	PermissionCouponsWrite         = "coupons:write"
+	PermissionOrganizationsRead    = "organizations:read"
+	PermissionUsageRead            = "usage:read"
+	PermissionInvoicesRead         = "invoices:read"
+	PermissionInvoicesWrite        = "invoices:write"
+	PermissionSubscriptionsRead    = "subscriptions:read"
+	PermissionSubscriptionsApprove = "subscriptions:approve"
+	PermissionSubscriptionsWrite   = "subscriptions:write"
+	PermissionSystemRead           = "system:read"
+	PermissionAuditRead            = "audit:read"
+	PermissionWebhooksRead         = "webhooks:read"
+	PermissionWebhooksWrite        = "webhooks:write"
+	PermissionStaffRead            = "staff:read"
+	PermissionStaffWrite           = "staff:write"
+)
+
+// AllPermissions lists every staff permission
+var AllPermissions = []string{
+	PermissionCustomersRead,
+	PermissionCustomersWrite,
+	PermissionPacksRead,
+	PermissionPacksWrite,
+	PermissionFeaturesRead,
+	PermissionFeaturesWrite,
+	PermissionCouponsRead,
+	PermissionCouponsWrite,
+	PermissionOrganizationsRead,
+	PermissionUsageRead,
+	PermissionInvoicesRead,
+	PermissionInvoicesWrite,
+	PermissionSubscriptionsRead,
+	PermissionSubscriptionsApprove,
+	PermissionSubscriptionsWrite,
+	PermissionSystemRead,
+	PermissionAuditRead,
+	PermissionWebhooksRead,
+	PermissionWebhooksWrite,
+	PermissionStaffRead,
+	PermissionStaffWrite,
+}
+
+// RolePermissions maps each staff role to the permissions it grants. Support
+// can read customer and billing data and approve requests; billing manages
+// packs, coupons and invoices.
+var RolePermissions = map[string][]string{
+	RoleSuperAdmin: AllPermissions,
+	RoleSupport: {
+		PermissionCustomersRead,
+		PermissionPacksRead,
+		PermissionFeaturesRead,
+		PermissionCouponsRead,
+		PermissionOrganizationsRead,
+		PermissionUsageRead,
+		PermissionInvoicesRead,
+		PermissionSubscriptionsRead,
+		PermissionSubscriptionsApprove,
+	},
+	RoleBilling: {
+		PermissionCustomersRead,
+		PermissionPacksRead,
+		PermissionPacksWrite,
+		PermissionFeaturesRead,
+		PermissionCouponsRead,
+		PermissionCouponsWrite,
+		PermissionOrganizationsRead,
+		PermissionUsageRead,
+		PermissionInvoicesRead,
+		PermissionInvoicesWrite,
+		PermissionSubscriptionsRead,
+	},
+}
+
+// IsStaffRole checks if role is one of the staff roles
+func IsStaffRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission checks if role grants permission
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
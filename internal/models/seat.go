@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Seat is a machine activation that consumes one of a pack's seats
+type Seat struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null;uniqueIndex:idx_seats_subscription_fingerprint"`
+	CustomerID     uint      `json:"customer_id" gorm:"not null;index"` // who activated the machine
+	Fingerprint    string    `json:"fingerprint" gorm:"not null;uniqueIndex:idx_seats_subscription_fingerprint"`
+	Hostname       string    `json:"hostname"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relationships
+	Subscription *Subscription `json:"subscription,omitempty" gorm:"foreignKey:SubscriptionID"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is a login session backing a rotating refresh token. Only the
+// SHA-256 hash of the refresh token is stored.
+type Session struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           uint       `json:"user_id" gorm:"not null;index"`
+	RefreshTokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	UserAgent        string     `json:"user_agent"`
+	IPAddress        string     `json:"ip_address"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// IsRevoked checks if the session has been logged out
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsValid checks if the session can still be used to refresh tokens
+func (s *Session) IsValid() bool {
+	return !s.IsRevoked() && s.ExpiresAt.After(time.Now())
+}
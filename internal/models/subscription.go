@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 )
 
@@ -12,41 +13,66 @@ const (
 	StatusActive    SubscriptionStatus = "active"
 	StatusInactive  SubscriptionStatus = "inactive"
 	StatusExpired   SubscriptionStatus = "expired"
+	StatusGrace     SubscriptionStatus = "grace"
+	StatusSuspended SubscriptionStatus = "suspended"
+	StatusTrial     SubscriptionStatus = "trial"
 )
 
+// CurrentStatuses are the statuses that occupy a customer's single current
+// subscription slot
+var CurrentStatuses = []SubscriptionStatus{StatusActive, StatusGrace, StatusSuspended, StatusTrial}
+
 type Subscription struct {
-	ID            uint               `json:"id" gorm:"primaryKey"`
-	CustomerID    uint               `json:"customer_id" gorm:"not null"`
-	PackID        uint               `json:"pack_id" gorm:"not null"`
-	Status        SubscriptionStatus `json:"status" gorm:"default:'requested'"`
-	RequestedAt   time.Time          `json:"requested_at"`
-	ApprovedAt    *time.Time         `json:"approved_at"`
-	AssignedAt    *time.Time         `json:"assigned_at"`
-	ExpiresAt     *time.Time         `json:"expires_at"`
-	DeactivatedAt *time.Time         `json:"deactivated_at"`
-	CreatedAt     time.Time          `json:"created_at"`
-	UpdatedAt     time.Time          `json:"updated_at"`
-	
+	ID             uint               `json:"id" gorm:"primaryKey"`
+	CustomerID     uint               `json:"customer_id" gorm:"not null"`
+	PackID         uint               `json:"pack_id" gorm:"not null"`
+	Status         SubscriptionStatus `json:"status" gorm:"default:'requested'"`
+	RequestedAt    time.Time          `json:"requested_at"`
+	ApprovedAt     *time.Time         `json:"approved_at"`
+	AssignedAt     *time.Time         `json:"assigned_at"`
+	ExpiresAt      *time.Time         `json:"expires_at"`
+	DeactivatedAt  *time.Time         `json:"deactivated_at"`
+	GraceEndsAt    *time.Time         `json:"grace_ends_at"`
+	SuspendedAt    *time.Time         `json:"suspended_at"`
+	AutoRenew      bool               `json:"auto_renew" gorm:"not null;default:false"`
+	RenewedFromID  *uint              `json:"renewed_from_id"`
+	ChangedFromID  *uint              `json:"changed_from_id"`
+	Currency       string             `json:"currency" gorm:"not null;default:USD"`
+	UnitAmount     int64              `json:"unit_amount" gorm:"not null;default:0"`
+	ProratedCredit int64              `json:"prorated_credit" gorm:"not null;default:0"`
+	CouponID       *uint              `json:"coupon_id"`
+	Discount       int64              `json:"discount" gorm:"not null;default:0"` // off the first period, in minor units
+	OrganizationID *uint              `json:"organization_id"`
+	Trial          bool               `json:"trial" gorm:"not null;default:false"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+
 	// Relationships
-	Customer *Customer         `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
-	Pack     *SubscriptionPack `json:"pack,omitempty" gorm:"foreignKey:PackID"`
+	Customer *Customer            `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+	Pack     *SubscriptionPack    `json:"pack,omitempty" gorm:"foreignKey:PackID"`
+	Coupon   *Coupon              `json:"coupon,omitempty" gorm:"foreignKey:CouponID"`
+	Seats    []*Seat              `json:"seats,omitempty" gorm:"foreignKey:SubscriptionID"`
+	Events   []*SubscriptionEvent `json:"events,omitempty" gorm:"foreignKey:SubscriptionID"`
 }
 
 // CanTransitionTo checks if the subscription can transition to the given status
 func (s *Subscription) CanTransitionTo(newStatus SubscriptionStatus) bool {
 	validTransitions := map[SubscriptionStatus][]SubscriptionStatus{
 		StatusRequested: {StatusApproved, StatusInactive},
+		StatusTrial:     {StatusActive, StatusInactive, StatusExpired},
 		StatusApproved:  {StatusActive, StatusInactive},
-		StatusActive:    {StatusInactive, StatusExpired},
+		StatusActive:    {StatusInactive, StatusExpired, StatusGrace, StatusSuspended},
+		StatusGrace:     {StatusActive, StatusInactive, StatusExpired, StatusSuspended},
+		StatusSuspended: {StatusActive, StatusGrace, StatusInactive, StatusExpired},
 		StatusInactive:  {StatusActive},
 		StatusExpired:   {StatusRequested},
 	}
-	
+
 	allowedStatuses, exists := validTransitions[s.Status]
 	if !exists {
 		return false
 	}
-	
+
 	for _, allowed := range allowedStatuses {
 		if allowed == newStatus {
 			return true
@@ -55,9 +81,42 @@ func (s *Subscription) CanTransitionTo(newStatus SubscriptionStatus) bool {
 	return false
 }
 
-// IsActive checks if the subscription is currently active
+// IsActive checks if the subscription currently grants access, which includes
+// the grace period after expiry but not suspension
 func (s *Subscription) IsActive() bool {
-	return s.Status == StatusActive && s.ExpiresAt != nil && s.ExpiresAt.After(time.Now())
+	now := time.Now()
+	switch s.Status {
+	case StatusActive, StatusTrial:
+		return s.ExpiresAt != nil && s.ExpiresAt.After(now)
+	case StatusGrace:
+		return s.GraceEndsAt != nil && s.GraceEndsAt.After(now)
+	}
+	return false
+}
+
+// IsInGrace checks if the subscription is in its grace period after expiry
+func (s *Subscription) IsInGrace() bool {
+	return s.Status == StatusGrace
+}
+
+// IsTrial checks if the subscription is in its trial period
+func (s *Subscription) IsTrial() bool {
+	return s.Status == StatusTrial
+}
+
+// IsSuspended checks if an admin has suspended the subscription
+func (s *Subscription) IsSuspended() bool {
+	return s.Status == StatusSuspended
+}
+
+// IsCurrent checks if the subscription holds the customer's current slot
+func (s *Subscription) IsCurrent() bool {
+	for _, status := range CurrentStatuses {
+		if s.Status == status {
+			return true
+		}
+	}
+	return false
 }
 
 // IsExpired checks if the subscription has expired
@@ -72,3 +131,40 @@ func (s *Subscription) CalculateExpiry(pack *SubscriptionPack) {
 		s.ExpiresAt = &expiry
 	}
 }
+
+// RemainingValue returns the unused share of price, in minor units, for the
+// time left between now and ExpiresAt
+func (s *Subscription) RemainingValue(price int64, now time.Time) int64 {
+	if s.AssignedAt == nil || s.ExpiresAt == nil || !s.ExpiresAt.After(now) {
+		return 0
+	}
+	total := s.ExpiresAt.Sub(*s.AssignedAt)
+	if total <= 0 {
+		return 0
+	}
+	remaining := s.ExpiresAt.Sub(now)
+	if remaining > total {
+		remaining = total
+	}
+	return int64(math.Round(float64(price) * float64(remaining) / float64(total)))
+}
+
+// BillingPeriod returns the monthly usage period containing now, counted from
+// when the subscription started
+func (s *Subscription) BillingPeriod(now time.Time) (time.Time, time.Time) {
+	anchor := s.RequestedAt
+	if s.AssignedAt != nil {
+		anchor = *s.AssignedAt
+	}
+	anchor = anchor.UTC()
+	if now.Before(anchor) {
+		return anchor, anchor.AddDate(0, 1, 0)
+	}
+
+	now = now.UTC()
+	months := (now.Year()-anchor.Year())*12 + int(now.Month()-anchor.Month())
+	for months > 0 && anchor.AddDate(0, months, 0).After(now) {
+		months--
+	}
+	return anchor.AddDate(0, months, 0), anchor.AddDate(0, months+1, 0)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// ActorType identifies who triggered a subscription status change
+type ActorType string
+
+const (
+	ActorAdmin    ActorType = "admin"
+	ActorCustomer ActorType = "customer"
+	ActorSDK      ActorType = "sdk"
+	ActorSystem   ActorType = "system"
+)
+
+// SubscriptionEvent is an append-only record of one subscription status change.
+// FromStatus is empty for the event that created the subscription.
+type SubscriptionEvent struct {
+	ID             uint               `json:"id" gorm:"primaryKey"`
+	SubscriptionID uint               `json:"subscription_id" gorm:"not null;index"`
+	FromStatus     SubscriptionStatus `json:"from_status"`
+	ToStatus       SubscriptionStatus `json:"to_status" gorm:"not null"`
+	ActorID        *uint              `json:"actor_id"`
+	ActorType      ActorType          `json:"actor_type" gorm:"not null"`
+	Reason         string             `json:"reason"`
+	CreatedAt      time.Time          `json:"created_at"`
+
+	// Relationships
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID"`
+}
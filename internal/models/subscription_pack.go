@@ -1,26 +1,157 @@
 package models
 
 import (
+	"regexp"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// DefaultCurrency is the currency of packs created without one, and of prices
+// recorded before packs had a currency
+const DefaultCurrency = "USD"
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	regionPattern   = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// SubscriptionPack is sold at UnitAmount, in minor units of Currency, unless
+// one of its Prices applies to the customer
 type SubscriptionPack struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	Name           string         `json:"name" gorm:"not null"`
-	Description    string         `json:"description"`
-	SKU            string         `json:"sku" gorm:"uniqueIndex;not null"`
-	Price          float64        `json:"price" gorm:"type:decimal(10,2);not null"`
-	ValidityMonths int            `json:"validity_months" gorm:"not null;check:validity_months >= 1 AND validity_months <= 12"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-	
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Name            string         `json:"name" gorm:"not null"`
+	Description     string         `json:"description"`
+	SKU             string         `json:"sku" gorm:"uniqueIndex;not null"`
+	Currency        string         `json:"currency" gorm:"not null;default:USD"`
+	UnitAmount      int64          `json:"unit_amount" gorm:"not null"`
+	ValidityMonths  int            `json:"validity_months" gorm:"not null;check:validity_months >= 1 AND validity_months <= 12"`
+	MaxSeats        int            `json:"max_seats" gorm:"not null;default:1"`
+	GracePeriodDays int            `json:"grace_period_days" gorm:"not null;default:0"`
+	TrialEnabled    bool           `json:"trial_enabled" gorm:"not null;default:false"`
+	TrialDays       int            `json:"trial_days" gorm:"not null;default:0"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
 	// Relationships
+	Prices        []PackPrice     `json:"prices,omitempty" gorm:"foreignKey:PackID"`
 	Subscriptions []*Subscription `json:"subscriptions,omitempty" gorm:"foreignKey:PackID"`
 }
 
+// PackPrice prices a pack in another currency, or overrides its price in one
+// region (an ISO 3166 country code). Region is empty for the currency-wide price.
+type PackPrice struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	PackID     uint      `json:"pack_id" gorm:"not null;uniqueIndex:idx_pack_prices_pack_currency_region"`
+	Currency   string    `json:"currency" gorm:"not null;uniqueIndex:idx_pack_prices_pack_currency_region"`
+	Region     string    `json:"region" gorm:"not null;default:'';uniqueIndex:idx_pack_prices_pack_currency_region"`
+	UnitAmount int64     `json:"unit_amount" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Price is what a customer pays for one period of a pack, in minor units of
+// Currency. Region is set when a regional override applies.
+type Price struct {
+	Currency   string `json:"currency"`
+	UnitAmount int64  `json:"unit_amount"`
+	Region     string `json:"region,omitempty"`
+}
+
+// IsValidCurrency checks for an upper case ISO 4217 code, e.g. EUR
+func IsValidCurrency(code string) bool {
+	return currencyPattern.MatchString(code)
+}
+
+// currencyExponents lists the ISO 4217 currencies whose minor unit is not a
+// hundredth of the major unit
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent returns how many decimal places the currency's minor unit
+// has: 0 for JPY, 3 for KWD and 2 for most others
+func CurrencyExponent(code string) int {
+	if exponent, ok := currencyExponents[code]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// IsValidRegion checks for an upper case ISO 3166 alpha-2 code, e.g. DE
+func IsValidRegion(code string) bool {
+	return regionPattern.MatchString(code)
+}
+
+// PriceFor returns the pack's price for a customer who prefers currency and is
+// in region; either may be empty. Prices must be loaded. A regional override
+// in the preferred currency comes first, then the currency-wide price. Without
+// a preference, a regional override in any currency beats the pack's own price.
+func (sp *SubscriptionPack) PriceFor(currency, region string) Price {
+	if currency == "" {
+		currency = sp.Currency
+		if region != "" {
+			for _, price := range sp.Prices {
+				if price.Region == region && price.Currency == currency {
+					return price.Price()
+				}
+			}
+			for _, price := range sp.Prices {
+				if price.Region == region {
+					return price.Price()
+				}
+			}
+		}
+	}
+
+	var currencyWide *PackPrice
+	for i, price := range sp.Prices {
+		if price.Currency != currency {
+			continue
+		}
+		if region != "" && price.Region == region {
+			return price.Price()
+		}
+		if price.Region == "" {
+			currencyWide = &sp.Prices[i]
+		}
+	}
+	if currencyWide != nil {
+		return currencyWide.Price()
+	}
+	return Price{Currency: sp.Currency, UnitAmount: sp.UnitAmount}
+}
+
+// Price returns the price the row sets
+func (pp *PackPrice) Price() Price {
+	return Price{Currency: pp.Currency, UnitAmount: pp.UnitAmount, Region: pp.Region}
+}
+
+// HasSeatAvailable checks if another machine can be activated given the seats in use
+func (sp *SubscriptionPack) HasSeatAvailable(seatsInUse int64) bool {
+	return seatsInUse < int64(sp.MaxSeats)
+}
+
+// OffersTrial checks if customers can start a trial of the pack
+func (sp *SubscriptionPack) OffersTrial() bool {
+	return sp.TrialEnabled && sp.TrialDays > 0
+}
+
+// GraceEndsAt returns when access ends for a subscription expiring at expiresAt,
+// or nil when the pack has no grace period
+func (sp *SubscriptionPack) GraceEndsAt(expiresAt time.Time) *time.Time {
+	if sp.GracePeriodDays <= 0 {
+		return nil
+	}
+	end := expiresAt.AddDate(0, 0, sp.GracePeriodDays)
+	return &end
+}
+
 // IsValid checks if the subscription pack is valid (not deleted)
 func (sp *SubscriptionPack) IsValid() bool {
 	return sp.DeletedAt.Time.IsZero()
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// UsageEvent is one usage report from the SDK. IdempotencyKey is unique per
+// customer so that retried reports are only counted once.
+type UsageEvent struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	CustomerID     uint      `json:"customer_id" gorm:"not null"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null"`
+	Metric         string    `json:"metric" gorm:"not null"`
+	Quantity       int64     `json:"quantity" gorm:"not null"`
+	IdempotencyKey string    `json:"idempotency_key" gorm:"not null"`
+	PeriodStart    time.Time `json:"period_start" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// UsageCounter is the running total of a metric for one subscription and
+// billing period
+type UsageCounter struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	CustomerID     uint      `json:"customer_id" gorm:"not null"`
+	SubscriptionID uint      `json:"subscription_id" gorm:"not null"`
+	Metric         string    `json:"metric" gorm:"not null"`
+	PeriodStart    time.Time `json:"period_start" gorm:"not null"`
+	PeriodEnd      time.Time `json:"period_end" gorm:"not null"`
+	Quantity       int64     `json:"quantity" gorm:"not null;default:0"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relationships
+	Customer *Customer `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
@@ -1,24 +1,24 @@
 package models
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	Email        string    `json:"email" gorm:"uniqueIndex;not null"`
-	Password     string    `json:"-" gorm:"not null"`
-	Role         string    `json:"role" gorm:"default:'customer'"`
-	APIKey       *string   `json:"-" gorm:"uniqueIndex"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
+	Password  string         `json:"-" gorm:"not null"`
+	Role      string         `json:"role" gorm:"default:'customer'"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 	
 	// Relationships
 	Customer *Customer `json:"customer,omitempty" gorm:"foreignKey:UserID"`
+	APIKeys  []*APIKey `json:"-" gorm:"foreignKey:UserID"`
 }
 
 func (u *User) HashPassword() error {
@@ -35,26 +35,16 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// IsAdmin checks if the user has one of the staff roles
 func (u *User) IsAdmin() bool {
-	return u.Role == "admin"
+	return IsStaffRole(u.Role)
 }
 
 func (u *User) IsCustomer() bool {
-	return u.Role == "customer"
+	return u.Role == RoleCustomer
 }
 
-// GenerateAPIKey generates a new API key for the user
-func (u *User) GenerateAPIKey() error {
-	bytes := make([]byte, 16)
-	if _, err := rand.Read(bytes); err != nil {
-		return err
-	}
-	apiKey := "sk-sdk-" + hex.EncodeToString(bytes)
-	u.APIKey = &apiKey
-	return nil
-}
-
-// HasAPIKey checks if the user has an API key
-func (u *User) HasAPIKey() bool {
-	return u.APIKey != nil && *u.APIKey != ""
+// HasPermission checks if the user's role grants permission
+func (u *User) HasPermission(permission string) bool {
+	return RoleHasPermission(u.Role, permission)
 }
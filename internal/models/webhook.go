@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+)
+
+// Webhook event types
+const (
+	EventSubscriptionRequested    = "subscription.requested"
+	EventSubscriptionApproved     = "subscription.approved"
+	EventSubscriptionActivated    = "subscription.activated"
+	EventSubscriptionDeactivated  = "subscription.deactivated"
+	EventSubscriptionExpired      = "subscription.expired"
+	EventSubscriptionRenewed      = "subscription.renewed"
+	EventSubscriptionGraceStarted = "subscription.grace_started"
+	EventSubscriptionSuspended    = "subscription.suspended"
+	EventSubscriptionResumed      = "subscription.resumed"
+	EventSubscriptionTrialStarted = "subscription.trial_started"
+	EventInvoiceCreated           = "invoice.created"
+	EventInvoicePaid              = "invoice.paid"
+	EventInvoiceVoided            = "invoice.voided"
+	EventPaymentSucceeded         = "payment.succeeded"
+	EventPaymentFailed            = "payment.failed"
+
+	EventOrganizationInvitationCreated = "organization.invitation_created"
+)
+
+// AllWebhookEvents lists every event a webhook endpoint can subscribe to
+var AllWebhookEvents = []string{
+	EventSubscriptionRequested,
+	EventSubscriptionApproved,
+	EventSubscriptionActivated,
+	EventSubscriptionDeactivated,
+	EventSubscriptionExpired,
+	EventSubscriptionRenewed,
+	EventSubscriptionGraceStarted,
+	EventSubscriptionSuspended,
+	EventSubscriptionResumed,
+	EventSubscriptionTrialStarted,
+	EventInvoiceCreated,
+	EventInvoicePaid,
+	EventInvoiceVoided,
+	EventPaymentSucceeded,
+	EventPaymentFailed,
+	EventOrganizationInvitationCreated,
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint is a URL registered by an admin to receive event notifications.
+// An empty Events list subscribes the endpoint to every event.
+type WebhookEndpoint struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	URL         string    `json:"url" gorm:"not null"`
+	Description string    `json:"description"`
+	Secret      string    `json:"-" gorm:"not null"`
+	Events      []string  `json:"events" gorm:"serializer:json"`
+	Active      bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the endpoint wants the given event type
+func (w *WebhookEndpoint) Subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidWebhookEvent reports whether eventType is a known event
+func IsValidWebhookEvent(eventType string) bool {
+	for _, e := range AllWebhookEvents {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one outbox entry: an event payload queued for an endpoint
+// together with the outcome of the latest attempt
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	EndpointID     uint       `json:"endpoint_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"not null"`
+	Status         string     `json:"status" gorm:"not null;default:'pending'"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Endpoint *WebhookEndpoint `json:"endpoint,omitempty" gorm:"foreignKey:EndpointID"`
+}
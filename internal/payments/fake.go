@@ -0,0 +1,131 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"cursor-ai-backend/internal/webhooks"
+)
+
+// FakeSignatureHeader carries the fake provider's webhook signature, in the
+// same "t=<unix>,v1=<hmac>" format as outgoing webhooks
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// fakeSignatureTolerance is how old a signed fake webhook may be
+const fakeSignatureTolerance = 5 * time.Minute
+
+// FakeProvider is a stand-in gateway for local development. Checkout sessions
+// live in memory and are settled by calling Complete, which produces the
+// signed webhook a real gateway would send.
+type FakeProvider struct {
+	secret  string
+	baseURL string
+
+	mu       sync.Mutex
+	sessions map[string]CheckoutRequest
+}
+
+// NewFakeProvider signs webhooks with secret. Checkout URLs point at the fake
+// checkout page served under baseURL.
+func NewFakeProvider(secret, baseURL string) *FakeProvider {
+	return &FakeProvider{
+		secret:   secret,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		sessions: make(map[string]CheckoutRequest),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	id, err := randomID("cs_fake_")
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.sessions[id] = req
+	p.mu.Unlock()
+
+	return &CheckoutSession{ID: id, URL: p.baseURL + "/payments/fake/checkout/" + id}, nil
+}
+
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (*Event, error) {
+	if !webhooks.Verify(p.secret, header.Get(FakeSignatureHeader), body, fakeSignatureTolerance) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.SessionID == "" {
+		return nil, ErrMalformedEvent
+	}
+	return &event, nil
+}
+
+// Session returns the request a checkout session was created for, unless
+// the session has expired
+func (p *FakeProvider) Session(sessionID string) (CheckoutRequest, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	req, ok := p.sessions[sessionID]
+	if ok && !time.Now().Before(req.ExpiresAt) {
+		delete(p.sessions, sessionID)
+		return CheckoutRequest{}, false
+	}
+	return req, ok
+}
+
+// Complete settles a checkout session, successfully or not, and returns the
+// signed webhook body and headers for the result. Each session settles once,
+// before it expires.
+func (p *FakeProvider) Complete(sessionID string, succeeded bool) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	req, ok := p.sessions[sessionID]
+	delete(p.sessions, sessionID)
+	p.mu.Unlock()
+	if !ok || !time.Now().Before(req.ExpiresAt) {
+		return nil, nil, ErrUnknownSession
+	}
+
+	id, err := randomID("evt_fake_")
+	if err != nil {
+		return nil, nil, err
+	}
+	event := Event{
+		ID:        id,
+		Type:      EventPaymentSucceeded,
+		SessionID: sessionID,
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+	}
+	if !succeeded {
+		event.Type = EventPaymentFailed
+		event.Reason = "Card declined"
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, webhooks.Sign(p.secret, time.Now(), body))
+	return body, header, nil
+}
+
+func randomID(prefix string) (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(bytes), nil
+}
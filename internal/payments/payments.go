@@ -0,0 +1,67 @@
+// Package payments defines the interface to payment gateways. A provider
+// creates hosted checkout sessions and authenticates the webhooks it sends
+// back when a payment settles.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+	ErrMalformedEvent   = errors.New("payments: malformed webhook event")
+	ErrUnknownSession   = errors.New("payments: unknown checkout session")
+)
+
+// EventType is the kind of payment webhook event
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+)
+
+// CheckoutRequest describes what the customer is asked to pay. Amount is in
+// minor currency units. Reference is echoed back in webhook events. The
+// session must not accept payment after ExpiresAt.
+type CheckoutRequest struct {
+	Reference     string
+	Amount        int64
+	Currency      string
+	Description   string
+	CustomerEmail string
+	SuccessURL    string
+	CancelURL     string
+	ExpiresAt     time.Time
+}
+
+// CheckoutSession is a hosted payment page the customer is sent to
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+// Event is a verified payment webhook event
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	SessionID string    `json:"session_id"`
+	Reference string    `json:"reference"`
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Provider is a payment gateway
+type Provider interface {
+	// Name identifies the provider in stored payments and webhook URLs
+	Name() string
+	// CreateCheckoutSession starts a hosted checkout for the request
+	CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error)
+	// ParseWebhook authenticates a webhook request and decodes its event,
+	// returning ErrInvalidSignature if it was not sent by the provider
+	ParseWebhook(header http.Header, body []byte) (*Event, error)
+}
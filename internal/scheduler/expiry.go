@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/services"
+)
+
+// ExpiryStatus describes the outcome of the most recent expiry run
+type ExpiryStatus struct {
+	Running     bool       `json:"running"`
+	Interval    string     `json:"interval"`
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastSuccess *time.Time `json:"last_success_at"`
+	LastExpired int        `json:"last_expired_count"`
+	LastError   string     `json:"last_error,omitempty"`
+	TotalRuns   int64      `json:"total_runs"`
+}
+
+// ExpiryScheduler periodically moves lapsed active subscriptions to expired
+type ExpiryScheduler struct {
+	db            *database.DB
+	subscriptions *services.SubscriptionService
+	interval      time.Duration
+
+	mu     sync.RWMutex
+	status ExpiryStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewExpiryScheduler(db *database.DB, subscriptions *services.SubscriptionService, interval time.Duration) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		db:            db,
+		subscriptions: subscriptions,
+		interval:      interval,
+		status:        ExpiryStatus{Interval: interval.String()},
+	}
+}
+
+// Start runs the expiry check immediately and then on every interval until Stop is called
+func (s *ExpiryScheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	s.status.Running = true
+	s.mu.Unlock()
+
+	go s.loop(ctx)
+}
+
+// Stop cancels the scheduler and waits for an in-flight run to finish
+func (s *ExpiryScheduler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
+	s.mu.Lock()
+	s.status.Running = false
+	s.mu.Unlock()
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *ExpiryScheduler) Status() ExpiryStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *ExpiryScheduler) loop(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.RunOnce(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce advances every subscription whose ExpiresAt or GraceEndsAt has passed
+func (s *ExpiryScheduler) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	expired, err := s.expireSubscriptions(ctx, now)
+
+	s.mu.Lock()
+	s.status.LastRunAt = &now
+	s.status.TotalRuns++
+	s.status.LastExpired = expired
+	if err != nil {
+		s.status.LastError = err.Error()
+	} else {
+		s.status.LastError = ""
+		s.status.LastSuccess = &now
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Expiry scheduler run failed: %v", err)
+	} else if expired > 0 {
+		log.Printf("Expiry scheduler expired %d subscription(s)", expired)
+	}
+	return expired, err
+}
+
+func (s *ExpiryScheduler) expireSubscriptions(ctx context.Context, now time.Time) (int, error) {
+	var subscriptions []models.Subscription
+	err := s.db.WithContext(ctx).
+		Where("(status IN ? AND expires_at IS NOT NULL AND expires_at <= ?) OR (status = ? AND grace_ends_at IS NOT NULL AND grace_ends_at <= ?)",
+			[]models.SubscriptionStatus{models.StatusActive, models.StatusSuspended, models.StatusTrial}, now, models.StatusGrace, now).
+		Find(&subscriptions).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, subscription := range subscriptions {
+		if ctx.Err() != nil {
+			return expired, ctx.Err()
+		}
+
+		// The service re-checks the status under lock, so a concurrent change is not overwritten
+		ok, err := s.subscriptions.Expire(subscription.ID, now)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
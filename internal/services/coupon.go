@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"cursor-ai-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponNotValid      = errors.New("coupon is not valid at this time")
+	ErrCouponExhausted     = errors.New("coupon has reached its redemption limit")
+	ErrCouponLimitReached  = errors.New("customer has already redeemed this coupon")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to this subscription")
+)
+
+// redeemCoupon applies the coupon with the given code to a new, priced
+// subscription on pack and counts the redemption. The coupon row is locked so
+// that concurrent requests cannot redeem it past its limit.
+func redeemCoupon(tx *gorm.DB, subscription *models.Subscription, pack *models.SubscriptionPack, code string) error {
+	var coupon models.Coupon
+	if err := forUpdate(tx).Where("code = ?", models.NormalizeCouponCode(code)).First(&coupon).Error; err != nil {
+		return notFound(err, ErrCouponNotFound)
+	}
+	if !coupon.IsValidAt(time.Now()) {
+		return ErrCouponNotValid
+	}
+	if coupon.IsExhausted() {
+		return ErrCouponExhausted
+	}
+	if !coupon.AppliesTo(pack.SKU) {
+		return ErrCouponNotApplicable
+	}
+
+	if coupon.MaxPerCustomer > 0 {
+		var redeemed int64
+		err := tx.Model(&models.Subscription{}).Where("customer_id = ? AND coupon_id = ?", subscription.CustomerID, coupon.ID).Count(&redeemed).Error
+		if err != nil {
+			return err
+		}
+		if redeemed >= int64(coupon.MaxPerCustomer) {
+			return ErrCouponLimitReached
+		}
+	}
+
+	discount, ok := coupon.Discount(subscription.UnitAmount, subscription.Currency)
+	if !ok {
+		return ErrCouponNotApplicable
+	}
+
+	subscription.CouponID = &coupon.ID
+	subscription.Discount = discount
+	return tx.Model(&coupon).Update("times_redeemed", gorm.Expr("times_redeemed + 1")).Error
+}
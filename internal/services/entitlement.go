@@ -0,0 +1,65 @@
+package services
+
+import (
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// EntitlementService resolves the feature catalog against subscription packs
+type EntitlementService struct {
+	db *database.DB
+}
+
+func NewEntitlementService(db *database.DB) *EntitlementService {
+	return &EntitlementService{db: db}
+}
+
+// ForPack resolves every catalog feature for the pack, using the feature
+// default where the pack sets no value
+func (s *EntitlementService) ForPack(packID uint) ([]models.Entitlement, error) {
+	return packEntitlements(s.db.DB, packID)
+}
+
+// ForCustomer returns the subscription the customer is licensed through and
+// the entitlements its pack grants
+func (s *EntitlementService) ForCustomer(customerID uint) (*models.Subscription, []models.Entitlement, error) {
+	customer := models.Customer{ID: customerID}
+	subscription, err := customer.GetLicensedSubscription(s.db.DB)
+	if err != nil {
+		return nil, nil, notFound(err, ErrNoActiveSubscription)
+	}
+
+	entitlements, err := packEntitlements(s.db.DB, subscription.PackID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subscription, entitlements, nil
+}
+
+func packEntitlements(db *gorm.DB, packID uint) ([]models.Entitlement, error) {
+	var features []models.Feature
+	if err := db.Order("key ASC").Find(&features).Error; err != nil {
+		return nil, err
+	}
+
+	var values []models.PackFeature
+	if err := db.Where("pack_id = ?", packID).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	granted := make(map[uint]string, len(values))
+	for _, v := range values {
+		granted[v.FeatureID] = v.Value
+	}
+
+	entitlements := make([]models.Entitlement, 0, len(features))
+	for i := range features {
+		raw, ok := granted[features[i].ID]
+		if !ok {
+			raw = features[i].DefaultValue
+		}
+		entitlements = append(entitlements, features[i].Entitlement(raw))
+	}
+	return entitlements, nil
+}
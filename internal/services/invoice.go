@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/webhooks"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvoiceNotFound          = errors.New("invoice not found")
+	ErrInvalidInvoiceTransition = errors.New("invoice cannot transition from its current status")
+)
+
+// InvoiceSettings configures newly issued invoices. TaxRate is a percentage.
+type InvoiceSettings struct {
+	TaxRate float64
+	DueDays int
+}
+
+// InvoiceService issues invoices for subscription periods and records payment
+// or voiding. Invoices are issued inside the subscription transition that
+// starts the billed period.
+type InvoiceService struct {
+	db       *database.DB
+	settings InvoiceSettings
+}
+
+func NewInvoiceService(db *database.DB, settings InvoiceSettings) *InvoiceService {
+	return &InvoiceService{db: db, settings: settings}
+}
+
+// Quote returns the total and currency of the invoice for the first period of
+// a requested subscription, which is what a customer pays up front at checkout
+func (s *InvoiceService) Quote(subscription *models.Subscription) (int64, string) {
+	subtotal := subscription.UnitAmount - min(subscription.Discount, subscription.UnitAmount)
+	return subtotal + s.tax(subtotal), subscription.Currency
+}
+
+// MarkPaid records payment of an open invoice
+func (s *InvoiceService) MarkPaid(invoiceID uint) (*models.Invoice, error) {
+	return s.transition(invoiceID, models.InvoicePaid, models.EventInvoicePaid, func(invoice *models.Invoice, now time.Time) {
+		invoice.PaidAt = &now
+	})
+}
+
+// Void cancels a draft or open invoice. Paid invoices cannot be voided.
+func (s *InvoiceService) Void(invoiceID uint) (*models.Invoice, error) {
+	return s.transition(invoiceID, models.InvoiceVoid, models.EventInvoiceVoided, func(invoice *models.Invoice, now time.Time) {
+		invoice.VoidedAt = &now
+	})
+}
+
+func (s *InvoiceService) transition(invoiceID uint, status models.InvoiceStatus, webhookEvent string, apply func(*models.Invoice, time.Time)) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Preload("Lines").First(&invoice, invoiceID).Error; err != nil {
+			return notFound(err, ErrInvoiceNotFound)
+		}
+		if !invoice.CanTransitionTo(status) {
+			return ErrInvalidInvoiceTransition
+		}
+
+		invoice.Status = status
+		apply(&invoice, time.Now())
+		if err := tx.Omit("Lines").Save(&invoice).Error; err != nil {
+			return err
+		}
+		return webhooks.Enqueue(tx, webhookEvent, &invoice)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// issue creates the open invoice, at the subscription's price, for the period
+// of subscription on pack that starts at start. credit is deducted as a
+// separate line, up to the price, and so is the subscription's coupon
+// discount on its first invoice.
+func (s *InvoiceService) issue(tx *gorm.DB, subscription *models.Subscription, pack *models.SubscriptionPack, start time.Time, reason string, credit int64) (*models.Invoice, error) {
+	number, err := nextInvoiceNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	price := subscription.UnitAmount
+	lines := []models.InvoiceLine{{
+		Description: fmt.Sprintf("%s (%s), %d month(s)", pack.Name, pack.SKU, pack.ValidityMonths),
+		Quantity:    1,
+		UnitAmount:  price,
+		Amount:      price,
+	}}
+	if credited := min(credit, price); credited > 0 {
+		lines = append(lines, models.InvoiceLine{
+			Description: "Prorated credit for unused time on the previous plan",
+			Quantity:    1,
+			UnitAmount:  -credited,
+			Amount:      -credited,
+		})
+		price -= credited
+	}
+	if reason == models.BillingSubscriptionCreate && subscription.CouponID != nil {
+		if discount := min(subscription.Discount, price); discount > 0 {
+			description := "Discount"
+			var coupon models.Coupon
+			if err := tx.Unscoped().First(&coupon, *subscription.CouponID).Error; err == nil {
+				description = "Discount (coupon " + coupon.Code + ")"
+			}
+			lines = append(lines, models.InvoiceLine{
+				Description: description,
+				Quantity:    1,
+				UnitAmount:  -discount,
+				Amount:      -discount,
+			})
+		}
+	}
+
+	var subtotal int64
+	for _, line := range lines {
+		subtotal += line.Amount
+	}
+	tax := s.tax(subtotal)
+
+	now := time.Now()
+	due := now.AddDate(0, 0, s.settings.DueDays)
+	invoice := &models.Invoice{
+		Number:         models.FormatInvoiceNumber(number),
+		CustomerID:     subscription.CustomerID,
+		SubscriptionID: subscription.ID,
+		Status:         models.InvoiceOpen,
+		BillingReason:  reason,
+		Currency:       subscription.Currency,
+		Subtotal:       subtotal,
+		TaxRate:        s.settings.TaxRate,
+		TaxAmount:      tax,
+		Total:          subtotal + tax,
+		PeriodStart:    &start,
+		PeriodEnd:      subscription.ExpiresAt,
+		IssuedAt:       &now,
+		DueAt:          &due,
+		Lines:          lines,
+	}
+	// Nothing to collect, e.g. when a credit covers the whole price
+	if invoice.Total == 0 {
+		invoice.Status = models.InvoicePaid
+		invoice.PaidAt = &now
+	}
+	if err := tx.Create(invoice).Error; err != nil {
+		return nil, err
+	}
+
+	if err := webhooks.Enqueue(tx, models.EventInvoiceCreated, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// tax returns the tax on subtotal at the configured rate
+func (s *InvoiceService) tax(subtotal int64) int64 {
+	return int64(math.Round(float64(subtotal) * s.settings.TaxRate / 100))
+}
+
+// nextInvoiceNumber increments the invoice sequence under the caller's
+// transaction, so a rolled back invoice does not use up a number
+func nextInvoiceNumber(tx *gorm.DB) (int64, error) {
+	err := tx.Model(&models.InvoiceSequence{}).Where("id = ?", 1).
+		Update("last_number", gorm.Expr("last_number + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var sequence models.InvoiceSequence
+	if err := tx.First(&sequence, 1).Error; err != nil {
+		return 0, err
+	}
+	return sequence.LastNumber, nil
+}
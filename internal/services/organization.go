@@ -0,0 +1,375 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/webhooks"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrganizationNotFound        = errors.New("organization not found")
+	ErrNotOrganizationManager      = errors.New("only organization owners and admins can do this")
+	ErrOrganizationOwner           = errors.New("the organization owner cannot be changed or removed")
+	ErrOrganizationHasSubscription = errors.New("organization has an open subscription")
+	ErrMemberNotFound              = errors.New("organization member not found")
+	ErrAlreadyMember               = errors.New("already a member of the organization")
+	ErrAlreadyInvited              = errors.New("email already has a pending invitation")
+	ErrNoMemberSeats               = errors.New("organization has no member seats left")
+	ErrInvitationNotFound          = errors.New("invitation not found")
+	ErrInvitationExpired           = errors.New("invitation has expired or was already accepted")
+	ErrInvitationEmailMismatch     = errors.New("invitation was sent to another email address")
+)
+
+// InvitationWebhook is the data of an organization.invitation_created event.
+// It carries the token so that a mailer can send the invitation.
+type InvitationWebhook struct {
+	Invitation       *models.OrganizationInvitation `json:"invitation"`
+	OrganizationName string                         `json:"organization_name"`
+	Token            string                         `json:"token"`
+}
+
+// OrganizationService manages organizations, their members and invitations.
+// Changes that add members lock the organization row, so that concurrent
+// invitations cannot take more member seats than its subscription's pack has.
+type OrganizationService struct {
+	db            *database.DB
+	subscriptions *SubscriptionService
+}
+
+func NewOrganizationService(db *database.DB, subscriptions *SubscriptionService) *OrganizationService {
+	return &OrganizationService{db: db, subscriptions: subscriptions}
+}
+
+// Create creates an organization owned by the customer
+func (s *OrganizationService) Create(customerID uint, name string) (*models.Organization, error) {
+	organization := &models.Organization{Name: name, CustomerID: customerID}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+		owner := models.OrganizationMember{OrganizationID: organization.ID, CustomerID: customerID, Role: models.OrgRoleOwner}
+		return tx.Create(&owner).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+// Membership returns the customer's membership of the organization. Customers
+// who are not members get ErrOrganizationNotFound.
+func (s *OrganizationService) Membership(organizationID, customerID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	err := s.db.Preload("Organization").Where("organization_id = ? AND customer_id = ?", organizationID, customerID).First(&member).Error
+	if err != nil {
+		return nil, notFound(err, ErrOrganizationNotFound)
+	}
+	if member.Organization == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	return &member, nil
+}
+
+// Delete deletes the organization with its members and invitations. Only the
+// owner can delete it, and only once it has no open subscriptions.
+func (s *OrganizationService) Delete(actor *models.OrganizationMember) error {
+	if actor.Role != models.OrgRoleOwner {
+		return ErrNotOrganizationManager
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		organization, err := lockOrganization(tx, actor.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		var open int64
+		err = tx.Model(&models.Subscription{}).
+			Where("organization_id = ? AND status NOT IN ?", organization.ID, []models.SubscriptionStatus{models.StatusInactive, models.StatusExpired}).
+			Count(&open).Error
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrOrganizationHasSubscription
+		}
+
+		if err := tx.Where("organization_id = ?", organization.ID).Delete(&models.OrganizationInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", organization.ID).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(organization).Error
+	})
+}
+
+// Invite invites an email address to join the organization with role. A
+// pending invitation holds a member seat until it is accepted or expires.
+// The token is returned only here.
+func (s *OrganizationService) Invite(actor *models.OrganizationMember, email, role string) (*models.OrganizationInvitation, string, error) {
+	if !actor.CanManage() {
+		return nil, "", ErrNotOrganizationManager
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	var invitation *models.OrganizationInvitation
+	var token string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		organization, err := lockOrganization(tx, actor.OrganizationID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		var members int64
+		err = tx.Model(&models.OrganizationMember{}).
+			Joins("JOIN customers ON customers.id = organization_members.customer_id").
+			Joins("JOIN users ON users.id = customers.user_id").
+			Where("organization_members.organization_id = ? AND LOWER(users.email) = ?", organization.ID, email).
+			Count(&members).Error
+		if err != nil {
+			return err
+		}
+		if members > 0 {
+			return ErrAlreadyMember
+		}
+
+		var invited int64
+		err = pendingInvitations(tx, organization.ID, now).Where("email = ?", email).Count(&invited).Error
+		if err != nil {
+			return err
+		}
+		if invited > 0 {
+			return ErrAlreadyInvited
+		}
+
+		if err := checkMemberSeats(tx, organization.ID, now); err != nil {
+			return err
+		}
+
+		var hash string
+		token, hash, err = models.NewInvitationToken()
+		if err != nil {
+			return err
+		}
+		invitation = &models.OrganizationInvitation{
+			OrganizationID: organization.ID,
+			Email:          email,
+			Role:           role,
+			TokenHash:      hash,
+			InvitedByID:    actor.CustomerID,
+			ExpiresAt:      now.Add(models.InvitationValidity),
+		}
+		if err := tx.Create(invitation).Error; err != nil {
+			return err
+		}
+
+		return webhooks.Enqueue(tx, models.EventOrganizationInvitationCreated, InvitationWebhook{
+			Invitation:       invitation,
+			OrganizationName: organization.Name,
+			Token:            token,
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return invitation, token, nil
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (s *OrganizationService) RevokeInvitation(actor *models.OrganizationMember, invitationID uint) error {
+	if !actor.CanManage() {
+		return ErrNotOrganizationManager
+	}
+
+	result := s.db.Where("id = ? AND organization_id = ? AND accepted_at IS NULL", invitationID, actor.OrganizationID).
+		Delete(&models.OrganizationInvitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation makes the customer a member of the organization the token
+// invites them to. The invitation must have been sent to the customer's email.
+func (s *OrganizationService) AcceptInvitation(customer *models.Customer, email, token string) (*models.OrganizationMember, error) {
+	var member *models.OrganizationMember
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.OrganizationInvitation
+		if err := tx.Where("token_hash = ?", models.HashAPIKey(token)).First(&invitation).Error; err != nil {
+			return notFound(err, ErrInvitationNotFound)
+		}
+		if !strings.EqualFold(invitation.Email, email) {
+			return ErrInvitationEmailMismatch
+		}
+
+		organization, err := lockOrganization(tx, invitation.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		// Re-read under lock so that the invitation is accepted once
+		if err := tx.First(&invitation, invitation.ID).Error; err != nil {
+			return notFound(err, ErrInvitationNotFound)
+		}
+		now := time.Now()
+		if !invitation.IsPending(now) {
+			return ErrInvitationExpired
+		}
+
+		var members int64
+		err = tx.Model(&models.OrganizationMember{}).Where("organization_id = ? AND customer_id = ?", organization.ID, customer.ID).Count(&members).Error
+		if err != nil {
+			return err
+		}
+		if members > 0 {
+			return ErrAlreadyMember
+		}
+
+		// The invitation already holds a seat, unless the subscription has
+		// since moved to a pack with fewer
+		invitation.AcceptedAt = &now
+		if err := tx.Save(&invitation).Error; err != nil {
+			return err
+		}
+		if err := checkMemberSeats(tx, organization.ID, now); err != nil {
+			return err
+		}
+
+		member = &models.OrganizationMember{OrganizationID: organization.ID, CustomerID: customer.ID, Role: invitation.Role}
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		member.Organization = organization
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// SetRole makes another member an admin or a plain member. Owners and admins
+// can change the role of members, and only the owner that of admins.
+func (s *OrganizationService) SetRole(actor *models.OrganizationMember, customerID uint, role string) (*models.OrganizationMember, error) {
+	if !actor.CanManage() {
+		return nil, ErrNotOrganizationManager
+	}
+
+	member, err := s.member(actor.OrganizationID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == models.OrgRoleOwner {
+		return nil, ErrOrganizationOwner
+	}
+	if member.Role == models.OrgRoleAdmin && actor.Role != models.OrgRoleOwner {
+		return nil, ErrNotOrganizationManager
+	}
+
+	member.Role = role
+	if err := s.db.Model(member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes a member from the organization. Owners and admins can
+// remove members, only the owner can remove admins, and anyone but the owner
+// can leave.
+func (s *OrganizationService) RemoveMember(actor *models.OrganizationMember, customerID uint) error {
+	member, err := s.member(actor.OrganizationID, customerID)
+	if err != nil {
+		return err
+	}
+	if member.Role == models.OrgRoleOwner {
+		return ErrOrganizationOwner
+	}
+	if member.ID != actor.ID {
+		if !actor.CanManage() || (member.Role == models.OrgRoleAdmin && actor.Role != models.OrgRoleOwner) {
+			return ErrNotOrganizationManager
+		}
+	}
+
+	return s.db.Delete(member).Error
+}
+
+// RequestSubscription requests a subscription owned by the organization
+func (s *OrganizationService) RequestSubscription(actor *models.OrganizationMember, packSKU, couponCode string, by Actor) (*models.Subscription, error) {
+	if !actor.CanManage() {
+		return nil, ErrNotOrganizationManager
+	}
+	return s.subscriptions.RequestForOrganization(actor.Organization, packSKU, couponCode, by)
+}
+
+// CurrentSubscription returns the organization's current subscription
+func (s *OrganizationService) CurrentSubscription(organizationID uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := s.db.Preload("Pack").Where("organization_id = ? AND status IN ?", organizationID, models.CurrentStatuses).First(&subscription).Error
+	if err != nil {
+		return nil, notFound(err, ErrNoActiveSubscription)
+	}
+	return &subscription, nil
+}
+
+func (s *OrganizationService) member(organizationID, customerID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := s.db.Where("organization_id = ? AND customer_id = ?", organizationID, customerID).First(&member).Error; err != nil {
+		return nil, notFound(err, ErrMemberNotFound)
+	}
+	return &member, nil
+}
+
+func lockOrganization(tx *gorm.DB, organizationID uint) (*models.Organization, error) {
+	var organization models.Organization
+	if err := forUpdate(tx).First(&organization, organizationID).Error; err != nil {
+		return nil, notFound(err, ErrOrganizationNotFound)
+	}
+	return &organization, nil
+}
+
+func pendingInvitations(tx *gorm.DB, organizationID uint, now time.Time) *gorm.DB {
+	return tx.Model(&models.OrganizationInvitation{}).
+		Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", organizationID, now)
+}
+
+// checkMemberSeats returns ErrNoMemberSeats unless the organization's members
+// and pending invitations leave a seat free. The seats are those of the
+// organization's current subscription's pack; without one, there is only
+// the owner's.
+func checkMemberSeats(tx *gorm.DB, organizationID uint, now time.Time) error {
+	seats := 1
+	var subscription models.Subscription
+	err := tx.Where("organization_id = ? AND status IN ?", organizationID, models.CurrentStatuses).First(&subscription).Error
+	if err == nil {
+		var pack models.SubscriptionPack
+		if err := tx.Unscoped().First(&pack, subscription.PackID).Error; err != nil {
+			return err
+		}
+		seats = pack.MaxSeats
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var members, invited int64
+	if err := tx.Model(&models.OrganizationMember{}).Where("organization_id = ?", organizationID).Count(&members).Error; err != nil {
+		return err
+	}
+	if err := pendingInvitations(tx, organizationID, now).Count(&invited).Error; err != nil {
+		return err
+	}
+	if members+invited >= int64(seats) {
+		return ErrNoMemberSeats
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/payments"
+	"cursor-ai-backend/internal/webhooks"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPaymentsDisabled      = errors.New("no payment provider is configured")
+	ErrUnknownProvider       = errors.New("unknown payment provider")
+	ErrPaymentNotFound       = errors.New("payment not found")
+	ErrPaymentAmountMismatch = errors.New("paid amount does not match the checkout")
+	ErrCheckoutInProgress    = errors.New("subscription has a pending checkout")
+	ErrAlreadyPaid           = errors.New("subscription has already been paid for")
+)
+
+// PaymentService takes payment for requested subscriptions through a payment
+// provider. A confirmed payment approves and assigns the subscription as the
+// system actor, and settles the invoice that assigning it issues.
+type PaymentService struct {
+	db            *database.DB
+	subscriptions *SubscriptionService
+	invoices      *InvoiceService
+	provider      payments.Provider
+	sessionTTL    time.Duration
+}
+
+// NewPaymentService creates the service. provider may be nil, in which case
+// checkout is disabled. Checkout sessions stop accepting payment after
+// sessionTTL.
+func NewPaymentService(db *database.DB, subscriptions *SubscriptionService, invoices *InvoiceService, provider payments.Provider, sessionTTL time.Duration) *PaymentService {
+	return &PaymentService{db: db, subscriptions: subscriptions, invoices: invoices, provider: provider, sessionTTL: sessionTTL}
+}
+
+// Checkout starts a hosted checkout for one of the customer's requested
+// subscriptions, or for the latest one when subscriptionID is 0. A
+// subscription has at most one pending checkout and none once it is paid for,
+// so that it cannot be paid twice. A pending checkout whose session has
+// expired is marked expired and replaced.
+func (s *PaymentService) Checkout(ctx context.Context, customerID, subscriptionID uint, successURL, cancelURL string) (*models.Payment, error) {
+	if s.provider == nil {
+		return nil, ErrPaymentsDisabled
+	}
+
+	var customer models.Customer
+	if err := s.db.Preload("User").First(&customer, customerID).Error; err != nil {
+		return nil, notFound(err, ErrCustomerNotFound)
+	}
+
+	var subscription models.Subscription
+	query := s.db.Where("customer_id = ?", customerID)
+	if subscriptionID != 0 {
+		query = query.Where("id = ?", subscriptionID)
+	} else {
+		query = query.Where("status = ?", models.StatusRequested).Order("id DESC")
+	}
+	if err := query.First(&subscription).Error; err != nil {
+		return nil, notFound(err, ErrSubscriptionNotFound)
+	}
+	if subscription.Status != models.StatusRequested {
+		return nil, ErrInvalidTransition
+	}
+	now := time.Now()
+	if err := s.checkoutAllowed(s.db.DB, subscription.ID, now); err != nil {
+		return nil, err
+	}
+
+	var pack models.SubscriptionPack
+	if err := s.db.First(&pack, subscription.PackID).Error; err != nil {
+		return nil, notFound(err, ErrPackNotFound)
+	}
+
+	amount, currency := s.invoices.Quote(&subscription)
+	req := payments.CheckoutRequest{
+		Reference:   strconv.FormatUint(uint64(subscription.ID), 10),
+		Amount:      amount,
+		Currency:    currency,
+		Description: pack.Name,
+		SuccessURL:  successURL,
+		CancelURL:   cancelURL,
+		ExpiresAt:   now.Add(s.sessionTTL),
+	}
+	if customer.User != nil {
+		req.CustomerEmail = customer.User.Email
+	}
+
+	session, err := s.provider.CreateCheckoutSession(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &models.Payment{
+		Provider:       s.provider.Name(),
+		SessionID:      session.ID,
+		CheckoutURL:    session.URL,
+		CustomerID:     customerID,
+		SubscriptionID: subscription.ID,
+		Status:         models.PaymentPending,
+		Amount:         amount,
+		Currency:       currency,
+		ExpiresAt:      &req.ExpiresAt,
+	}
+
+	// Check again under the subscription lock in case a concurrent checkout
+	// got there first; the session just created is then never handed out
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var locked models.Subscription
+		if err := forUpdate(tx).First(&locked, subscription.ID).Error; err != nil {
+			return notFound(err, ErrSubscriptionNotFound)
+		}
+		if locked.Status != models.StatusRequested {
+			return ErrInvalidTransition
+		}
+		if err := s.expireCheckouts(tx, subscription.ID, now); err != nil {
+			return err
+		}
+		if err := s.checkoutAllowed(tx, subscription.ID, now); err != nil {
+			return err
+		}
+		return tx.Create(payment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// checkoutAllowed returns ErrAlreadyPaid when the subscription has a
+// successful payment, or ErrCheckoutInProgress when it has a pending one whose
+// session has not expired at now
+func (s *PaymentService) checkoutAllowed(db *gorm.DB, subscriptionID uint, now time.Time) error {
+	var open []models.Payment
+	err := db.Where("subscription_id = ? AND status IN ?", subscriptionID, []models.PaymentStatus{models.PaymentPending, models.PaymentSucceeded}).
+		Find(&open).Error
+	if err != nil {
+		return err
+	}
+	inProgress := false
+	for _, payment := range open {
+		if payment.Status == models.PaymentSucceeded {
+			return ErrAlreadyPaid
+		}
+		if !s.sessionExpired(&payment, now) {
+			inProgress = true
+		}
+	}
+	if inProgress {
+		return ErrCheckoutInProgress
+	}
+	return nil
+}
+
+// expireCheckouts marks the subscription's pending payments whose session has
+// expired at now as expired
+func (s *PaymentService) expireCheckouts(tx *gorm.DB, subscriptionID uint, now time.Time) error {
+	var pending []models.Payment
+	if err := tx.Where("subscription_id = ? AND status = ?", subscriptionID, models.PaymentPending).Find(&pending).Error; err != nil {
+		return err
+	}
+	for i := range pending {
+		if !s.sessionExpired(&pending[i], now) {
+			continue
+		}
+		err := tx.Model(&pending[i]).Updates(map[string]interface{}{
+			"status":         models.PaymentExpired,
+			"failure_reason": "Checkout session expired",
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionExpired reports whether the payment's checkout session no longer
+// accepts payment at now. Payments from before sessions had an expiry are
+// given sessionTTL from when they were created.
+func (s *PaymentService) sessionExpired(payment *models.Payment, now time.Time) bool {
+	expiresAt := payment.CreatedAt.Add(s.sessionTTL)
+	if payment.ExpiresAt != nil {
+		expiresAt = *payment.ExpiresAt
+	}
+	return !now.Before(expiresAt)
+}
+
+// HandleWebhook authenticates and applies a webhook from the named provider.
+// Redelivered events are acknowledged without being applied twice, but a
+// successful payment whose subscription could not be activated is retried.
+func (s *PaymentService) HandleWebhook(providerName string, header http.Header, body []byte) (*models.Payment, error) {
+	if s.provider == nil || s.provider.Name() != providerName {
+		return nil, ErrUnknownProvider
+	}
+
+	event, err := s.provider.ParseWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment models.Payment
+	mismatch := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := forUpdate(tx).Where("provider = ? AND session_id = ?", providerName, event.SessionID).First(&payment).Error
+		if err != nil {
+			return notFound(err, ErrPaymentNotFound)
+		}
+		// A provider may confirm a payment made just before its session
+		// expired; the money was taken, so it still counts
+		expiredPaid := payment.Status == models.PaymentExpired && event.Type == payments.EventPaymentSucceeded
+		if payment.Status != models.PaymentPending && !expiredPaid {
+			return nil
+		}
+
+		switch event.Type {
+		case payments.EventPaymentSucceeded:
+			if event.Amount != payment.Amount || !strings.EqualFold(event.Currency, payment.Currency) {
+				mismatch = true
+				payment.Status = models.PaymentFailed
+				payment.FailureReason = "Paid amount does not match the checkout"
+			} else {
+				now := time.Now()
+				payment.Status = models.PaymentSucceeded
+				payment.PaidAt = &now
+				payment.FailureReason = ""
+			}
+		case payments.EventPaymentFailed:
+			payment.Status = models.PaymentFailed
+			payment.FailureReason = event.Reason
+		default:
+			return nil
+		}
+
+		payment.EventID = event.ID
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+		webhookEvent := models.EventPaymentFailed
+		if payment.Status == models.PaymentSucceeded {
+			webhookEvent = models.EventPaymentSucceeded
+		}
+		return webhooks.Enqueue(tx, webhookEvent, &payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mismatch {
+		return &payment, ErrPaymentAmountMismatch
+	}
+
+	if payment.Status == models.PaymentSucceeded {
+		if err := s.fulfil(&payment); err != nil {
+			return &payment, err
+		}
+	}
+	return &payment, nil
+}
+
+// fulfil approves and assigns the paid subscription, as an admin would, and
+// marks the invoice issued on assignment as paid by this payment
+func (s *PaymentService) fulfil(payment *models.Payment) error {
+	if payment.InvoiceID != nil {
+		return nil
+	}
+
+	var subscription models.Subscription
+	if err := s.db.First(&subscription, payment.SubscriptionID).Error; err != nil {
+		return notFound(err, ErrSubscriptionNotFound)
+	}
+
+	// Another delivery of the same event may be a step ahead of us
+	if subscription.Status == models.StatusRequested {
+		if _, err := s.subscriptions.Approve(subscription.ID, SystemActor); err != nil && !errors.Is(err, ErrInvalidTransition) {
+			return err
+		}
+	}
+	if _, err := s.subscriptions.Assign(subscription.ID, SystemActor); err != nil && !errors.Is(err, ErrInvalidTransition) {
+		return err
+	}
+
+	var invoice models.Invoice
+	err := s.db.Where("subscription_id = ? AND billing_reason = ?", subscription.ID, models.BillingSubscriptionCreate).
+		Order("id DESC").First(&invoice).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	// The tax rate may have changed since checkout; leave such invoices open
+	if invoice.Total != payment.Amount {
+		return nil
+	}
+	if invoice.Status == models.InvoiceOpen {
+		if _, err := s.invoices.MarkPaid(invoice.ID); err != nil && !errors.Is(err, ErrInvalidInvoiceTransition) {
+			return err
+		}
+	}
+
+	payment.InvoiceID = &invoice.ID
+	return s.db.Model(payment).Update("invoice_id", invoice.ID).Error
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/database/databasetest"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/payments"
+)
+
+func TestCheckoutReplacesExpiredSession(t *testing.T) {
+	databasetest.Drivers(t, databasetest.SQLite, func(t *testing.T, db *database.DB) {
+		invoices := NewInvoiceService(db, InvoiceSettings{DueDays: 14})
+		subscriptions := NewSubscriptionService(db, invoices)
+		provider := payments.NewFakeProvider("whsec_test", "http://localhost")
+		service := NewPaymentService(db, subscriptions, invoices, provider, time.Hour)
+
+		customer := createCustomer(t, db, "checkout@example.com")
+		pack := createPack(t, db, "PRO")
+		subscription, err := subscriptions.Request(customer.ID, pack.SKU, "", SystemActor)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+
+		first, err := service.Checkout(context.Background(), customer.ID, subscription.ID, "", "")
+		if err != nil {
+			t.Fatalf("checkout: %v", err)
+		}
+		if _, err := service.Checkout(context.Background(), customer.ID, subscription.ID, "", ""); !errors.Is(err, ErrCheckoutInProgress) {
+			t.Fatalf("second checkout: got %v, want %v", err, ErrCheckoutInProgress)
+		}
+
+		// Once the session has expired, a new checkout replaces it
+		err = db.Model(first).Update("expires_at", time.Now().Add(-time.Minute)).Error
+		if err != nil {
+			t.Fatalf("expire session: %v", err)
+		}
+		second, err := service.Checkout(context.Background(), customer.ID, subscription.ID, "", "")
+		if err != nil {
+			t.Fatalf("checkout after expiry: %v", err)
+		}
+		if second.SessionID == first.SessionID {
+			t.Fatalf("checkout reused the expired session")
+		}
+
+		var expired models.Payment
+		if err := db.First(&expired, first.ID).Error; err != nil {
+			t.Fatalf("load first payment: %v", err)
+		}
+		if expired.Status != models.PaymentExpired {
+			t.Fatalf("first payment is %q, want %q", expired.Status, models.PaymentExpired)
+		}
+
+		// Paying the new session activates the subscription; no further
+		// checkout is possible
+		body, header, err := provider.Complete(second.SessionID, true)
+		if err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		paid, err := service.HandleWebhook(provider.Name(), header, body)
+		if err != nil {
+			t.Fatalf("handle webhook: %v", err)
+		}
+		if paid.Status != models.PaymentSucceeded {
+			t.Fatalf("payment is %q, want %q", paid.Status, models.PaymentSucceeded)
+		}
+		var active models.Subscription
+		if err := db.First(&active, subscription.ID).Error; err != nil {
+			t.Fatalf("load subscription: %v", err)
+		}
+		if active.Status != models.StatusActive {
+			t.Fatalf("subscription is %q, want %q", active.Status, models.StatusActive)
+		}
+	})
+}
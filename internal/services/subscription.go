@@ -0,0 +1,913 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/webhooks"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCustomerNotFound         = errors.New("customer not found")
+	ErrPackNotFound             = errors.New("subscription pack not found")
+	ErrSubscriptionNotFound     = errors.New("subscription not found")
+	ErrNoActiveSubscription     = errors.New("no active subscription found")
+	ErrActiveSubscriptionExists = errors.New("customer already has an active subscription")
+	ErrInvalidTransition        = errors.New("subscription cannot transition from its current status")
+	ErrSamePack                 = errors.New("subscription is already on this pack")
+	ErrPlanChangePending        = errors.New("a plan change is already scheduled")
+	ErrTrialNotOffered          = errors.New("subscription pack does not offer a trial")
+	ErrTrialUsed                = errors.New("customer has already had a trial of this pack")
+)
+
+// When a plan change takes effect
+const (
+	ChangeImmediately = "immediate"
+	ChangeAtEndOfTerm = "end_of_term"
+)
+
+// PlanChange describes the result of ChangePlan. Amounts are in minor units
+// of Currency.
+type PlanChange struct {
+	Previous       *models.Subscription `json:"previous"`
+	Subscription   *models.Subscription `json:"subscription"`
+	Effective      string               `json:"effective"`
+	Currency       string               `json:"currency"`
+	ProratedCredit int64                `json:"prorated_credit"`
+	AmountDue      int64                `json:"amount_due"`
+	Invoice        *models.Invoice      `json:"invoice,omitempty"`
+}
+
+// Actor identifies who is performing a subscription transition. UserID is nil
+// for system-initiated changes.
+type Actor struct {
+	Type   models.ActorType
+	UserID *uint
+}
+
+// SystemActor is used for transitions made by background jobs
+var SystemActor = Actor{Type: models.ActorSystem}
+
+// SubscriptionService runs every subscription state transition in a database
+// transaction. The customer row is locked first so that concurrent transitions
+// for one customer are serialized, and the partial unique index on active
+// subscriptions backs this up at the database level. Every transition appends
+// a SubscriptionEvent and queues webhook deliveries in the same transaction,
+// and transitions that start a paid period issue its invoice.
+type SubscriptionService struct {
+	db       *database.DB
+	invoices *InvoiceService
+}
+
+func NewSubscriptionService(db *database.DB, invoices *InvoiceService) *SubscriptionService {
+	return &SubscriptionService{db: db, invoices: invoices}
+}
+
+// Request creates a requested subscription for the customer and pack SKU,
+// redeeming the coupon with couponCode unless it is empty
+func (s *SubscriptionService) Request(customerID uint, packSKU, couponCode string, actor Actor) (*models.Subscription, error) {
+	return s.request(customerID, nil, packSKU, couponCode, actor)
+}
+
+// RequestForOrganization creates a requested subscription owned by the
+// organization, billed to its owner's customer account
+func (s *SubscriptionService) RequestForOrganization(organization *models.Organization, packSKU, couponCode string, actor Actor) (*models.Subscription, error) {
+	return s.request(organization.CustomerID, &organization.ID, packSKU, couponCode, actor)
+}
+
+func (s *SubscriptionService) request(customerID uint, organizationID *uint, packSKU, couponCode string, actor Actor) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		customer, err := lockCustomer(tx, customerID)
+		if err != nil {
+			return err
+		}
+		if organizationID != nil {
+			if _, err := lockOrganization(tx, *organizationID); err != nil {
+				return err
+			}
+		}
+
+		subscription = &models.Subscription{
+			CustomerID:     customer.ID,
+			Status:         models.StatusRequested,
+			RequestedAt:    time.Now(),
+			OrganizationID: organizationID,
+		}
+		if ownerHasCurrentSubscription(tx, subscription) {
+			return ErrActiveSubscriptionExists
+		}
+
+		var pack models.SubscriptionPack
+		if err := tx.Where("sku = ?", packSKU).First(&pack).Error; err != nil {
+			return notFound(err, ErrPackNotFound)
+		}
+
+		subscription.PackID = pack.ID
+		if err := priceSubscription(tx, subscription, &pack); err != nil {
+			return err
+		}
+		if couponCode != "" {
+			if err := redeemCoupon(tx, subscription, &pack, couponCode); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(subscription).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, subscription, "", actor, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// StartTrial starts a trial of the pack for the customer right away, without
+// approval. A customer gets one trial per pack.
+func (s *SubscriptionService) StartTrial(customerID uint, packSKU string, actor Actor) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		customer, err := lockCustomer(tx, customerID)
+		if err != nil {
+			return err
+		}
+
+		var pack models.SubscriptionPack
+		if err := tx.Where("sku = ?", packSKU).First(&pack).Error; err != nil {
+			return notFound(err, ErrPackNotFound)
+		}
+		if !pack.OffersTrial() {
+			return ErrTrialNotOffered
+		}
+
+		var trials int64
+		err = tx.Model(&models.Subscription{}).Where("customer_id = ? AND pack_id = ? AND trial = ?", customer.ID, pack.ID, true).Count(&trials).Error
+		if err != nil {
+			return err
+		}
+		if trials > 0 {
+			return ErrTrialUsed
+		}
+
+		if customer.HasActiveSubscription(tx) {
+			return ErrActiveSubscriptionExists
+		}
+
+		now := time.Now()
+		expiry := now.AddDate(0, 0, pack.TrialDays)
+		subscription = &models.Subscription{
+			CustomerID:  customer.ID,
+			PackID:      pack.ID,
+			Status:      models.StatusTrial,
+			Trial:       true,
+			RequestedAt: now,
+			AssignedAt:  &now,
+			ExpiresAt:   &expiry,
+		}
+		if err := priceSubscription(tx, subscription, &pack); err != nil {
+			return err
+		}
+		if err := tx.Create(subscription).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, subscription, "", actor, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Approve moves a requested subscription to approved. Approving a trial
+// converts it to a paid active subscription whose validity starts now.
+func (s *SubscriptionService) Approve(subscriptionID uint, actor Actor) (*models.Subscription, error) {
+	return s.transition(subscriptionID, actor, "", func(tx *gorm.DB, subscription *models.Subscription) error {
+		if subscription.IsTrial() {
+			return s.convertTrial(tx, subscription)
+		}
+		if !subscription.CanTransitionTo(models.StatusApproved) {
+			return ErrInvalidTransition
+		}
+
+		now := time.Now()
+		subscription.Status = models.StatusApproved
+		subscription.ApprovedAt = &now
+		return tx.Save(subscription).Error
+	})
+}
+
+// Assign activates an approved subscription and starts its validity period.
+// An inactive subscription can be assigned again, unless it was cancelled
+// before it was ever assigned.
+func (s *SubscriptionService) Assign(subscriptionID uint, actor Actor) (*models.Subscription, error) {
+	return s.transition(subscriptionID, actor, "", func(tx *gorm.DB, subscription *models.Subscription) error {
+		if !subscription.CanTransitionTo(models.StatusActive) {
+			return ErrInvalidTransition
+		}
+		if subscription.Status == models.StatusInactive && subscription.AssignedAt == nil {
+			return ErrInvalidTransition
+		}
+
+		if ownerHasCurrentSubscription(tx, subscription) {
+			return ErrActiveSubscriptionExists
+		}
+
+		var pack models.SubscriptionPack
+		if err := tx.Unscoped().First(&pack, subscription.PackID).Error; err != nil {
+			return notFound(err, ErrPackNotFound)
+		}
+
+		now := time.Now()
+		subscription.Status = models.StatusActive
+		subscription.AssignedAt = &now
+		subscription.CalculateExpiry(&pack)
+		if err := tx.Save(subscription).Error; err != nil {
+			return err
+		}
+		_, err := s.invoices.issue(tx, subscription, &pack, now, models.BillingSubscriptionCreate, 0)
+		return err
+	})
+}
+
+// Unassign deactivates a current subscription
+func (s *SubscriptionService) Unassign(subscriptionID uint, actor Actor, reason string) (*models.Subscription, error) {
+	return s.transition(subscriptionID, actor, reason, func(tx *gorm.DB, subscription *models.Subscription) error {
+		return deactivate(tx, subscription, actor)
+	})
+}
+
+// Deactivate deactivates the customer's own current subscription
+func (s *SubscriptionService) Deactivate(customerID uint, actor Actor, reason string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCustomer(tx, customerID); err != nil {
+			return err
+		}
+
+		err := forUpdate(tx).Where("customer_id = ? AND organization_id IS NULL AND status IN ?", customerID, models.CurrentStatuses).First(&subscription).Error
+		if err != nil {
+			return notFound(err, ErrNoActiveSubscription)
+		}
+		from := subscription.Status
+		if err := deactivate(tx, &subscription, actor); err != nil {
+			return err
+		}
+		return recordEvent(tx, &subscription, from, actor, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Expire advances a subscription that has lapsed at now and reports whether
+// it changed. An active subscription past ExpiresAt is succeeded by a scheduled
+// plan change or an automatic renewal when there is one, otherwise it enters
+// the pack's grace period, or expires when the pack has none. Subscriptions in
+// grace expire at GraceEndsAt, and suspended ones at ExpiresAt.
+func (s *SubscriptionService) Expire(subscriptionID uint, now time.Time) (bool, error) {
+	changed := false
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		subscription, err := lockSubscription(tx, subscriptionID)
+		if err != nil {
+			return err
+		}
+
+		from := subscription.Status
+		switch {
+		case from == models.StatusActive && lapsed(subscription.ExpiresAt, now):
+		case from == models.StatusGrace && lapsed(subscription.GraceEndsAt, now):
+		case from == models.StatusSuspended && lapsed(subscription.ExpiresAt, now):
+			// Suspended subscriptions are neither renewed nor given grace, nor
+			// replaced by a scheduled plan change
+			changed = true
+			if err := expire(tx, subscription, "Validity period ended while suspended"); err != nil {
+				return err
+			}
+			return cancelScheduledChange(tx, subscription, SystemActor)
+		case from == models.StatusTrial && lapsed(subscription.ExpiresAt, now):
+			changed = true
+			return expire(tx, subscription, "Trial period ended")
+		default:
+			return nil
+		}
+		changed = true
+
+		// A scheduled plan change takes precedence over renewing the old pack
+		var next models.Subscription
+		err = tx.Where("changed_from_id = ? AND status = ?", subscription.ID, models.StatusApproved).First(&next).Error
+		if err == nil {
+			if err := expire(tx, subscription, "Validity period ended"); err != nil {
+				return err
+			}
+			return s.startScheduledChange(tx, subscription, &next)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if subscription.AutoRenew {
+			if err := expire(tx, subscription, "Validity period ended"); err != nil {
+				return err
+			}
+			return s.autoRenew(tx, subscription)
+		}
+
+		if from == models.StatusActive {
+			var pack models.SubscriptionPack
+			if err := tx.Unscoped().First(&pack, subscription.PackID).Error; err != nil {
+				return err
+			}
+			if graceEnd := pack.GraceEndsAt(*subscription.ExpiresAt); graceEnd != nil && graceEnd.After(now) {
+				subscription.Status = models.StatusGrace
+				subscription.GraceEndsAt = graceEnd
+				if err := tx.Save(subscription).Error; err != nil {
+					return err
+				}
+				reason := "Validity period ended, grace period until " + graceEnd.Format(time.RFC3339)
+				return recordEvent(tx, subscription, from, SystemActor, reason)
+			}
+		}
+
+		reason := "Validity period ended"
+		if from == models.StatusGrace {
+			reason = "Grace period ended"
+		}
+		return expire(tx, subscription, reason)
+	})
+	return changed, err
+}
+
+// Suspend blocks access for an active or in-grace subscription, for example
+// for non-payment, without ending it
+func (s *SubscriptionService) Suspend(subscriptionID uint, actor Actor, reason string) (*models.Subscription, error) {
+	return s.transition(subscriptionID, actor, reason, func(tx *gorm.DB, subscription *models.Subscription) error {
+		if subscription.Status != models.StatusActive && subscription.Status != models.StatusGrace {
+			return ErrInvalidTransition
+		}
+
+		now := time.Now()
+		subscription.Status = models.StatusSuspended
+		subscription.SuspendedAt = &now
+		return tx.Save(subscription).Error
+	})
+}
+
+// Resume lifts a suspension. The subscription returns to active, to its grace
+// period, or expires, depending on how much time is left.
+func (s *SubscriptionService) Resume(subscriptionID uint, actor Actor, reason string) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		var err error
+		subscription, err = lockSubscription(tx, subscriptionID)
+		if err != nil {
+			return err
+		}
+		if subscription.Status != models.StatusSuspended {
+			return ErrInvalidTransition
+		}
+
+		var pack models.SubscriptionPack
+		if err := tx.Unscoped().First(&pack, subscription.PackID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		subscription.SuspendedAt = nil
+		subscription.Status = models.StatusActive
+		if lapsed(subscription.ExpiresAt, now) {
+			subscription.Status = models.StatusExpired
+			if graceEnd := pack.GraceEndsAt(*subscription.ExpiresAt); graceEnd != nil && graceEnd.After(now) {
+				subscription.Status = models.StatusGrace
+				subscription.GraceEndsAt = graceEnd
+			}
+		}
+		if err := tx.Save(subscription).Error; err != nil {
+			return err
+		}
+
+		webhookEvent := models.EventSubscriptionResumed
+		if subscription.Status == models.StatusExpired {
+			webhookEvent = models.EventSubscriptionExpired
+		}
+		if err := appendEvent(tx, subscription, models.StatusSuspended, actor, reason, webhookEvent); err != nil {
+			return err
+		}
+		if subscription.Status == models.StatusExpired {
+			return cancelScheduledChange(tx, subscription, actor)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Renew extends the customer's own active or in-grace subscription by one validity
+// period of its pack, counted from the current expiry so no time is lost
+func (s *SubscriptionService) Renew(customerID uint, actor Actor) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		if _, err := lockCustomer(tx, customerID); err != nil {
+			return err
+		}
+
+		err := forUpdate(tx).Where("customer_id = ? AND organization_id IS NULL AND status IN ?", customerID, models.CurrentStatuses).First(&subscription).Error
+		if err != nil {
+			return notFound(err, ErrNoActiveSubscription)
+		}
+		if subscription.IsSuspended() || subscription.IsTrial() {
+			return ErrInvalidTransition
+		}
+
+		// A pack that has been withdrawn cannot be renewed
+		var pack models.SubscriptionPack
+		if err := tx.First(&pack, subscription.PackID).Error; err != nil {
+			return notFound(err, ErrPackNotFound)
+		}
+
+		// Renewing from grace counts from the old expiry, so the grace days are not free
+		now := time.Now()
+		start := now
+		if subscription.ExpiresAt != nil {
+			start = *subscription.ExpiresAt
+		}
+		expiry := start.AddDate(0, pack.ValidityMonths, 0)
+		if !expiry.After(now) {
+			start = now
+			expiry = now.AddDate(0, pack.ValidityMonths, 0)
+		}
+
+		from := subscription.Status
+		subscription.Status = models.StatusActive
+		subscription.ExpiresAt = &expiry
+		subscription.GraceEndsAt = nil
+		if err := priceSubscription(tx, &subscription, &pack); err != nil {
+			return err
+		}
+		if err := tx.Save(&subscription).Error; err != nil {
+			return err
+		}
+		if _, err := s.invoices.issue(tx, &subscription, &pack, start, models.BillingSubscriptionRenewal, 0); err != nil {
+			return err
+		}
+
+		reason := "Renewed until " + expiry.Format(time.RFC3339)
+		return appendEvent(tx, &subscription, from, actor, reason, models.EventSubscriptionRenewed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// ChangePlan moves an active or in-grace subscription to another pack. The old and new
+// subscriptions are linked through ChangedFromID. An immediate change ends the
+// old subscription now and credits its unused time against the new pack's
+// price; an end-of-term change queues an approved subscription that the expiry
+// scheduler activates when the current one expires.
+func (s *SubscriptionService) ChangePlan(subscriptionID uint, packSKU, effective string, actor Actor) (*PlanChange, error) {
+	var change *PlanChange
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		current, err := lockSubscription(tx, subscriptionID)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.StatusActive && current.Status != models.StatusGrace {
+			return ErrInvalidTransition
+		}
+
+		var pack models.SubscriptionPack
+		if err := tx.Where("sku = ?", packSKU).First(&pack).Error; err != nil {
+			return notFound(err, ErrPackNotFound)
+		}
+		if pack.ID == current.PackID {
+			return ErrSamePack
+		}
+
+		var pending int64
+		if err := tx.Model(&models.Subscription{}).Where("changed_from_id = ? AND status = ?", current.ID, models.StatusApproved).Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrPlanChangePending
+		}
+
+		var currentPack models.SubscriptionPack
+		if err := tx.Unscoped().First(&currentPack, current.PackID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		next := &models.Subscription{
+			CustomerID:     current.CustomerID,
+			PackID:         pack.ID,
+			RequestedAt:    now,
+			ApprovedAt:     &now,
+			AutoRenew:      current.AutoRenew,
+			ChangedFromID:  &current.ID,
+			OrganizationID: current.OrganizationID,
+		}
+		if err := priceSubscription(tx, next, &pack); err != nil {
+			return err
+		}
+		change = &PlanChange{Previous: current, Subscription: next, Effective: effective, Currency: next.Currency, AmountDue: next.UnitAmount}
+
+		reason := fmt.Sprintf("Plan change from %s to %s", currentPack.SKU, pack.SKU)
+		if effective == ChangeAtEndOfTerm {
+			next.Status = models.StatusApproved
+			if err := tx.Create(next).Error; err != nil {
+				return err
+			}
+			return recordEvent(tx, next, "", actor, reason+" scheduled for end of term")
+		}
+
+		// Unused time can only be credited against a price in the same currency
+		var credit int64
+		if current.Currency == next.Currency {
+			credit = min(current.RemainingValue(current.UnitAmount, now), next.UnitAmount)
+		}
+		from := current.Status
+		if err := deactivate(tx, current, actor); err != nil {
+			return err
+		}
+		if err := recordEvent(tx, current, from, actor, reason); err != nil {
+			return err
+		}
+
+		next.Status = models.StatusActive
+		next.AssignedAt = &now
+		next.ProratedCredit = credit
+		next.CalculateExpiry(&pack)
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		if err := carryOverSeats(tx, current, next, pack.MaxSeats); err != nil {
+			return err
+		}
+		change.Invoice, err = s.invoices.issue(tx, next, &pack, now, models.BillingPlanChange, credit)
+		if err != nil {
+			return err
+		}
+
+		change.ProratedCredit = credit
+		change.AmountDue = next.UnitAmount - credit
+		return recordEvent(tx, next, "", actor, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// SetAutoRenew turns automatic renewal of the customer's own current subscription on or off
+func (s *SubscriptionService) SetAutoRenew(customerID uint, enabled bool) (*models.Subscription, error) {
+	var subscription models.Subscription
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		err := tx.Where("customer_id = ? AND organization_id IS NULL AND status IN ?", customerID, models.CurrentStatuses).First(&subscription).Error
+		if err != nil {
+			return notFound(err, ErrNoActiveSubscription)
+		}
+
+		subscription.AutoRenew = enabled
+		return tx.Model(&subscription).Update("auto_renew", enabled).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// transition locks the subscription's customer and the subscription itself,
+// applies fn inside the same transaction and records an event if the status changed
+func (s *SubscriptionService) transition(subscriptionID uint, actor Actor, reason string, fn func(tx *gorm.DB, subscription *models.Subscription) error) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.inTransaction(func(tx *gorm.DB) error {
+		var err error
+		subscription, err = lockSubscription(tx, subscriptionID)
+		if err != nil {
+			return err
+		}
+
+		from := subscription.Status
+		if err := fn(tx, subscription); err != nil {
+			return err
+		}
+		if subscription.Status == from {
+			return nil
+		}
+		return recordEvent(tx, subscription, from, actor, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// inTransaction runs fn in a transaction and reports a violation of the
+// one-active-subscription index as ErrActiveSubscriptionExists
+func (s *SubscriptionService) inTransaction(fn func(tx *gorm.DB) error) error {
+	err := s.db.Transaction(fn)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrActiveSubscriptionExists
+	}
+	return err
+}
+
+// lockSubscription locks the subscription's customer and then the subscription
+func lockSubscription(tx *gorm.DB, subscriptionID uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := tx.First(&subscription, subscriptionID).Error; err != nil {
+		return nil, notFound(err, ErrSubscriptionNotFound)
+	}
+
+	if _, err := lockCustomer(tx, subscription.CustomerID); err != nil {
+		return nil, err
+	}
+
+	// Re-read under lock in case the row changed while we waited
+	if err := forUpdate(tx).First(&subscription, subscriptionID).Error; err != nil {
+		return nil, notFound(err, ErrSubscriptionNotFound)
+	}
+	return &subscription, nil
+}
+
+// autoRenew creates the active successor of an expired auto-renewing subscription.
+// Withdrawn packs are not renewed.
+func (s *SubscriptionService) autoRenew(tx *gorm.DB, previous *models.Subscription) error {
+	var pack models.SubscriptionPack
+	if err := tx.First(&pack, previous.PackID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	start := now
+	if previous.ExpiresAt != nil {
+		start = *previous.ExpiresAt
+	}
+	successor := &models.Subscription{
+		CustomerID:     previous.CustomerID,
+		PackID:         previous.PackID,
+		Status:         models.StatusActive,
+		RequestedAt:    now,
+		ApprovedAt:     &now,
+		AssignedAt:     &start,
+		AutoRenew:      true,
+		RenewedFromID:  &previous.ID,
+		OrganizationID: previous.OrganizationID,
+	}
+	successor.CalculateExpiry(&pack)
+	if err := priceSubscription(tx, successor, &pack); err != nil {
+		return err
+	}
+	if err := tx.Create(successor).Error; err != nil {
+		return err
+	}
+
+	if err := carryOverSeats(tx, previous, successor, pack.MaxSeats); err != nil {
+		return err
+	}
+	if _, err := s.invoices.issue(tx, successor, &pack, start, models.BillingSubscriptionRenewal, 0); err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("Automatic renewal of subscription %d", previous.ID)
+	return recordEvent(tx, successor, "", SystemActor, reason)
+}
+
+// startScheduledChange activates the approved subscription queued by an
+// end-of-term plan change, starting when the previous one ended
+func (s *SubscriptionService) startScheduledChange(tx *gorm.DB, previous, next *models.Subscription) error {
+	var pack models.SubscriptionPack
+	if err := tx.Unscoped().First(&pack, next.PackID).Error; err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if previous.ExpiresAt != nil {
+		start = *previous.ExpiresAt
+	}
+	next.Status = models.StatusActive
+	next.AssignedAt = &start
+	next.CalculateExpiry(&pack)
+	if err := tx.Save(next).Error; err != nil {
+		return err
+	}
+	if err := carryOverSeats(tx, previous, next, pack.MaxSeats); err != nil {
+		return err
+	}
+	if _, err := s.invoices.issue(tx, next, &pack, start, models.BillingPlanChange, 0); err != nil {
+		return err
+	}
+
+	return recordEvent(tx, next, models.StatusApproved, SystemActor, "Scheduled plan change took effect")
+}
+
+// priceSubscription sets the subscription's price to what its customer pays
+// for pack now, given their currency preference and country
+func priceSubscription(tx *gorm.DB, subscription *models.Subscription, pack *models.SubscriptionPack) error {
+	var customer models.Customer
+	if err := tx.First(&customer, subscription.CustomerID).Error; err != nil {
+		return notFound(err, ErrCustomerNotFound)
+	}
+	if err := tx.Where("pack_id = ?", pack.ID).Find(&pack.Prices).Error; err != nil {
+		return err
+	}
+
+	price := pack.PriceFor(customer.Currency, customer.Country)
+	subscription.Currency = price.Currency
+	subscription.UnitAmount = price.UnitAmount
+	return nil
+}
+
+// carryOverSeats moves activated machines to the successor subscription so
+// clients keep working. When the new pack allows fewer seats, the most
+// recently seen machines are kept and the rest are released.
+func carryOverSeats(tx *gorm.DB, previous, next *models.Subscription, maxSeats int) error {
+	var seats []models.Seat
+	if err := tx.Where("subscription_id = ?", previous.ID).Order("last_seen_at DESC, id DESC").Find(&seats).Error; err != nil {
+		return err
+	}
+
+	for i := range seats {
+		if i >= maxSeats {
+			if err := tx.Delete(&seats[i]).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(&seats[i]).Update("subscription_id", next.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertTrial turns a trial into a paid active subscription
+func (s *SubscriptionService) convertTrial(tx *gorm.DB, subscription *models.Subscription) error {
+	var pack models.SubscriptionPack
+	if err := tx.Unscoped().First(&pack, subscription.PackID).Error; err != nil {
+		return notFound(err, ErrPackNotFound)
+	}
+
+	now := time.Now()
+	subscription.Status = models.StatusActive
+	subscription.ApprovedAt = &now
+	subscription.AssignedAt = &now
+	subscription.CalculateExpiry(&pack)
+	if err := priceSubscription(tx, subscription, &pack); err != nil {
+		return err
+	}
+	if err := tx.Save(subscription).Error; err != nil {
+		return err
+	}
+	_, err := s.invoices.issue(tx, subscription, &pack, now, models.BillingSubscriptionCreate, 0)
+	return err
+}
+
+// deactivate ends a current subscription along with any plan change scheduled
+// to replace it
+func deactivate(tx *gorm.DB, subscription *models.Subscription, actor Actor) error {
+	if !subscription.IsCurrent() || !subscription.CanTransitionTo(models.StatusInactive) {
+		return ErrInvalidTransition
+	}
+
+	now := time.Now()
+	subscription.Status = models.StatusInactive
+	subscription.DeactivatedAt = &now
+	if err := tx.Save(subscription).Error; err != nil {
+		return err
+	}
+	return cancelScheduledChange(tx, subscription, actor)
+}
+
+// cancelScheduledChange deactivates the approved subscription queued by an
+// end-of-term plan change of previous, which ended before the change took
+// effect, so that it cannot be assigned later
+func cancelScheduledChange(tx *gorm.DB, previous *models.Subscription, actor Actor) error {
+	var scheduled []models.Subscription
+	err := forUpdate(tx).Where("changed_from_id = ? AND status = ?", previous.ID, models.StatusApproved).Find(&scheduled).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range scheduled {
+		scheduled[i].Status = models.StatusInactive
+		scheduled[i].DeactivatedAt = &now
+		if err := tx.Save(&scheduled[i]).Error; err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("Scheduled plan change cancelled because subscription %d ended", previous.ID)
+		if err := recordEvent(tx, &scheduled[i], models.StatusApproved, actor, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhookEvents maps the status a subscription moved to onto the webhook event it fires
+var webhookEvents = map[models.SubscriptionStatus]string{
+	models.StatusRequested: models.EventSubscriptionRequested,
+	models.StatusApproved:  models.EventSubscriptionApproved,
+	models.StatusActive:    models.EventSubscriptionActivated,
+	models.StatusInactive:  models.EventSubscriptionDeactivated,
+	models.StatusExpired:   models.EventSubscriptionExpired,
+	models.StatusGrace:     models.EventSubscriptionGraceStarted,
+	models.StatusSuspended: models.EventSubscriptionSuspended,
+	models.StatusTrial:     models.EventSubscriptionTrialStarted,
+}
+
+// SubscriptionWebhook is the data of a subscription webhook event
+type SubscriptionWebhook struct {
+	Subscription   *models.Subscription      `json:"subscription"`
+	PreviousStatus models.SubscriptionStatus `json:"previous_status"`
+	ActorType      models.ActorType          `json:"actor_type"`
+	Reason         string                    `json:"reason,omitempty"`
+}
+
+// expire moves a lapsed subscription to expired and records why
+func expire(tx *gorm.DB, subscription *models.Subscription, reason string) error {
+	from := subscription.Status
+	subscription.Status = models.StatusExpired
+	if err := tx.Save(subscription).Error; err != nil {
+		return err
+	}
+	return recordEvent(tx, subscription, from, SystemActor, reason)
+}
+
+// lapsed reports whether the deadline t has passed at now
+func lapsed(t *time.Time, now time.Time) bool {
+	return t != nil && !t.After(now)
+}
+
+// recordEvent appends the subscription event and queues the webhook for the new status
+func recordEvent(tx *gorm.DB, subscription *models.Subscription, from models.SubscriptionStatus, actor Actor, reason string) error {
+	return appendEvent(tx, subscription, from, actor, reason, webhookEvents[subscription.Status])
+}
+
+// appendEvent appends the subscription event and queues webhookEvent, if any
+func appendEvent(tx *gorm.DB, subscription *models.Subscription, from models.SubscriptionStatus, actor Actor, reason, webhookEvent string) error {
+	err := tx.Create(&models.SubscriptionEvent{
+		SubscriptionID: subscription.ID,
+		FromStatus:     from,
+		ToStatus:       subscription.Status,
+		ActorID:        actor.UserID,
+		ActorType:      actor.Type,
+		Reason:         reason,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if webhookEvent == "" {
+		return nil
+	}
+	return webhooks.Enqueue(tx, webhookEvent, SubscriptionWebhook{
+		Subscription:   subscription,
+		PreviousStatus: from,
+		ActorType:      actor.Type,
+		Reason:         reason,
+	})
+}
+
+// ownerHasCurrentSubscription checks if the owner of subscription, its
+// organization or else its customer, already has a current subscription
+func ownerHasCurrentSubscription(tx *gorm.DB, subscription *models.Subscription) bool {
+	if subscription.OrganizationID != nil {
+		organization := models.Organization{ID: *subscription.OrganizationID}
+		return organization.HasCurrentSubscription(tx)
+	}
+	customer := models.Customer{ID: subscription.CustomerID}
+	return customer.HasActiveSubscription(tx)
+}
+
+func lockCustomer(tx *gorm.DB, customerID uint) (*models.Customer, error) {
+	var customer models.Customer
+	if err := forUpdate(tx).First(&customer, customerID).Error; err != nil {
+		return nil, notFound(err, ErrCustomerNotFound)
+	}
+	return &customer, nil
+}
+
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+func notFound(err, sentinel error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return sentinel
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/database/databasetest"
+	"cursor-ai-backend/internal/models"
+)
+
+func TestAssignConcurrentlyActivatesOneSubscription(t *testing.T) {
+	databasetest.Drivers(t, databasetest.SQLiteFile, func(t *testing.T, db *database.DB) {
+		const requests = 8
+
+		subscriptions := NewSubscriptionService(db, NewInvoiceService(db, InvoiceSettings{DueDays: 14}))
+		customer := createCustomer(t, db, "concurrent@example.com")
+		pack := createPack(t, db, "PRO")
+
+		ids := make([]uint, requests)
+		for i := range ids {
+			subscription, err := subscriptions.Request(customer.ID, pack.SKU, "", SystemActor)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if _, err := subscriptions.Approve(subscription.ID, SystemActor); err != nil {
+				t.Fatalf("approve: %v", err)
+			}
+			ids[i] = subscription.ID
+		}
+
+		errs := make([]error, requests)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i, id := range ids {
+			wg.Add(1)
+			go func(i int, id uint) {
+				defer wg.Done()
+				<-start
+				_, errs[i] = subscriptions.Assign(id, SystemActor)
+			}(i, id)
+		}
+		close(start)
+		wg.Wait()
+
+		assigned := 0
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				assigned++
+			case errors.Is(err, ErrActiveSubscriptionExists):
+			default:
+				t.Errorf("assign subscription %d: unexpected error %v", ids[i], err)
+			}
+		}
+		if assigned != 1 {
+			t.Fatalf("%d subscriptions were assigned, want 1", assigned)
+		}
+
+		var active int64
+		err := db.Model(&models.Subscription{}).Where("customer_id = ? AND status IN ?", customer.ID, models.CurrentStatuses).Count(&active).Error
+		if err != nil {
+			t.Fatalf("count active: %v", err)
+		}
+		if active != 1 {
+			t.Fatalf("customer has %d current subscriptions, want 1", active)
+		}
+	})
+}
+
+func TestScheduledPlanChangeEndsWithCurrentSubscription(t *testing.T) {
+	ends := map[string]func(s *SubscriptionService, db *database.DB, current *models.Subscription) error{
+		"unassigned": func(s *SubscriptionService, db *database.DB, current *models.Subscription) error {
+			_, err := s.Unassign(current.ID, SystemActor, "")
+			return err
+		},
+		"deactivated by the customer": func(s *SubscriptionService, db *database.DB, current *models.Subscription) error {
+			_, err := s.Deactivate(current.CustomerID, SystemActor, "")
+			return err
+		},
+		"expired while suspended": func(s *SubscriptionService, db *database.DB, current *models.Subscription) error {
+			if _, err := s.Suspend(current.ID, SystemActor, ""); err != nil {
+				return err
+			}
+			_, err := s.Expire(current.ID, current.ExpiresAt.Add(time.Second))
+			return err
+		},
+		"resumed after its expiry": func(s *SubscriptionService, db *database.DB, current *models.Subscription) error {
+			if _, err := s.Suspend(current.ID, SystemActor, ""); err != nil {
+				return err
+			}
+			if err := db.Model(current).Update("expires_at", time.Now().Add(-time.Hour)).Error; err != nil {
+				return err
+			}
+			_, err := s.Resume(current.ID, SystemActor, "")
+			return err
+		},
+	}
+
+	for name, end := range ends {
+		t.Run(name, func(t *testing.T) {
+			databasetest.Drivers(t, databasetest.SQLite, func(t *testing.T, db *database.DB) {
+				subscriptions := NewSubscriptionService(db, NewInvoiceService(db, InvoiceSettings{DueDays: 14}))
+				customer := createCustomer(t, db, "change@example.com")
+				pack := createPack(t, db, "PRO")
+				createPack(t, db, "TEAM")
+
+				current, err := subscriptions.Request(customer.ID, pack.SKU, "", SystemActor)
+				if err != nil {
+					t.Fatalf("request: %v", err)
+				}
+				if _, err := subscriptions.Approve(current.ID, SystemActor); err != nil {
+					t.Fatalf("approve: %v", err)
+				}
+				if current, err = subscriptions.Assign(current.ID, SystemActor); err != nil {
+					t.Fatalf("assign: %v", err)
+				}
+				change, err := subscriptions.ChangePlan(current.ID, "TEAM", ChangeAtEndOfTerm, SystemActor)
+				if err != nil {
+					t.Fatalf("change plan: %v", err)
+				}
+
+				if err := end(subscriptions, db, current); err != nil {
+					t.Fatalf("end current subscription: %v", err)
+				}
+
+				var next models.Subscription
+				if err := db.First(&next, change.Subscription.ID).Error; err != nil {
+					t.Fatalf("load scheduled subscription: %v", err)
+				}
+				if next.Status != models.StatusInactive {
+					t.Fatalf("scheduled subscription is %q, want %q", next.Status, models.StatusInactive)
+				}
+				var events int64
+				err = db.Model(&models.SubscriptionEvent{}).
+					Where("subscription_id = ? AND from_status = ? AND to_status = ?", next.ID, models.StatusApproved, models.StatusInactive).
+					Count(&events).Error
+				if err != nil {
+					t.Fatalf("count events: %v", err)
+				}
+				if events != 1 {
+					t.Errorf("%d cancellation events recorded, want 1", events)
+				}
+				if _, err := subscriptions.Assign(next.ID, SystemActor); !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("assign cancelled subscription: got %v, want %v", err, ErrInvalidTransition)
+				}
+			})
+		})
+	}
+}
+
+func createCustomer(t *testing.T, db *database.DB, email string) *models.Customer {
+	t.Helper()
+	user := &models.User{Email: email, Password: "secret", Role: models.RoleCustomer}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	customer := &models.Customer{UserID: user.ID, Name: email}
+	if err := db.Create(customer).Error; err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	return customer
+}
+
+func createPack(t *testing.T, db *database.DB, sku string) *models.SubscriptionPack {
+	t.Helper()
+	pack := &models.SubscriptionPack{
+		Name:           sku,
+		SKU:            sku,
+		Currency:       models.DefaultCurrency,
+		UnitAmount:     1000,
+		ValidityMonths: 1,
+		MaxSeats:       1,
+	}
+	if err := db.Create(pack).Error; err != nil {
+		t.Fatalf("create pack: %v", err)
+	}
+	return pack
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownMetric        = errors.New("metric is not a limit feature")
+	ErrQuotaExceeded        = errors.New("usage quota exceeded")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different usage report")
+	ErrNoAccess             = errors.New("subscription does not currently grant access")
+)
+
+// Quota is the state of a metered limit for the current billing period.
+// Remaining is -1 when the limit is unlimited.
+type Quota struct {
+	Metric      string    `json:"metric"`
+	Limit       int64     `json:"limit"`
+	Unlimited   bool      `json:"unlimited"`
+	Used        int64     `json:"used"`
+	Remaining   int64     `json:"remaining"`
+	Allowed     bool      `json:"allowed"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+// UsageRecord is the result of Record. Duplicate is set when the idempotency
+// key had already been recorded and nothing was counted.
+type UsageRecord struct {
+	Event     *models.UsageEvent `json:"event"`
+	Quota     Quota              `json:"quota"`
+	Duplicate bool               `json:"duplicate"`
+}
+
+// UsageService records metered usage against the limit features of the
+// customer's pack. Usage is counted per subscription and monthly billing period.
+type UsageService struct {
+	db *database.DB
+}
+
+func NewUsageService(db *database.DB) *UsageService {
+	return &UsageService{db: db}
+}
+
+// Record counts quantity of metric for the customer's current subscription.
+// Reports that would take usage past the limit are rejected with
+// ErrQuotaExceeded, and a repeated idempotency key is not counted again. The
+// subscription is locked while its counter is updated.
+func (s *UsageService) Record(customerID uint, metric string, quantity int64, idempotencyKey string, now time.Time) (*UsageRecord, error) {
+	var record *UsageRecord
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCustomer(tx, customerID); err != nil {
+			return err
+		}
+
+		var existing models.UsageEvent
+		err := tx.Where("customer_id = ? AND idempotency_key = ?", customerID, idempotencyKey).First(&existing).Error
+		if err == nil {
+			if existing.Metric != metric || existing.Quantity != quantity {
+				return ErrIdempotencyKeyReused
+			}
+			quota, err := s.quota(tx, customerID, metric, 0, now)
+			if err != nil {
+				return err
+			}
+			record = &UsageRecord{Event: &existing, Quota: *quota, Duplicate: true}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		subscription, limit, err := currentLimit(tx, customerID, metric)
+		if err != nil {
+			return err
+		}
+
+		// Members of an organization share its counters, so the customer lock
+		// alone does not serialize them; the subscription row does
+		if err := forUpdate(tx).First(subscription, subscription.ID).Error; err != nil {
+			return err
+		}
+		if !subscription.IsActive() {
+			return ErrNoAccess
+		}
+
+		start, end := subscription.BillingPeriod(now)
+		counter := models.UsageCounter{
+			CustomerID:     subscription.CustomerID,
+			SubscriptionID: subscription.ID,
+			Metric:         metric,
+			PeriodStart:    start,
+			PeriodEnd:      end,
+		}
+		err = tx.Where("subscription_id = ? AND metric = ? AND period_start = ?", subscription.ID, metric, start).First(&counter).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if limit != models.UnlimitedValue && counter.Quantity+quantity > limit {
+			return ErrQuotaExceeded
+		}
+
+		event := &models.UsageEvent{
+			CustomerID:     customerID,
+			SubscriptionID: subscription.ID,
+			Metric:         metric,
+			Quantity:       quantity,
+			IdempotencyKey: idempotencyKey,
+			PeriodStart:    start,
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		counter.Quantity += quantity
+		if err := tx.Save(&counter).Error; err != nil {
+			return err
+		}
+
+		record = &UsageRecord{Event: event, Quota: newQuota(metric, limit, counter.Quantity, 0, start, end)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Check reports whether quantity more of metric fits in the customer's quota
+// for the current billing period
+func (s *UsageService) Check(customerID uint, metric string, quantity int64, now time.Time) (*Quota, error) {
+	return s.quota(s.db.DB, customerID, metric, quantity, now)
+}
+
+func (s *UsageService) quota(db *gorm.DB, customerID uint, metric string, quantity int64, now time.Time) (*Quota, error) {
+	subscription, limit, err := currentLimit(db, customerID, metric)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := subscription.BillingPeriod(now)
+	var counter models.UsageCounter
+	err = db.Where("subscription_id = ? AND metric = ? AND period_start = ?", subscription.ID, metric, start).First(&counter).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	quota := newQuota(metric, limit, counter.Quantity, quantity, start, end)
+	return &quota, nil
+}
+
+// currentLimit loads the subscription the customer is licensed through and the
+// limit its pack sets for metric. Members of an organization share its quota.
+func currentLimit(db *gorm.DB, customerID uint, metric string) (*models.Subscription, int64, error) {
+	customer := models.Customer{ID: customerID}
+	subscription, err := customer.GetLicensedSubscription(db)
+	if err != nil {
+		return nil, 0, notFound(err, ErrNoActiveSubscription)
+	}
+	if !subscription.IsActive() {
+		return nil, 0, ErrNoAccess
+	}
+
+	var feature models.Feature
+	if err := db.Where("key = ?", metric).First(&feature).Error; err != nil {
+		return nil, 0, notFound(err, ErrUnknownMetric)
+	}
+	if feature.Type != models.FeatureTypeLimit {
+		return nil, 0, ErrUnknownMetric
+	}
+
+	raw := feature.DefaultValue
+	var value models.PackFeature
+	err = db.Where("pack_id = ? AND feature_id = ?", subscription.PackID, feature.ID).First(&value).Error
+	if err == nil {
+		raw = value.Value
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, err
+	}
+
+	limit, _ := feature.Entitlement(raw).Value.(int64)
+	return subscription, limit, nil
+}
+
+func newQuota(metric string, limit, used, quantity int64, start, end time.Time) Quota {
+	quota := Quota{
+		Metric:      metric,
+		Limit:       limit,
+		Used:        used,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+	if limit == models.UnlimitedValue {
+		quota.Unlimited = true
+		quota.Remaining = -1
+		quota.Allowed = true
+		return quota
+	}
+
+	quota.Remaining = limit - used
+	if quota.Remaining < 0 {
+		quota.Remaining = 0
+	}
+	quota.Allowed = used+quantity <= limit
+	return quota
+}
@@ -0,0 +1,230 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/models"
+)
+
+const (
+	// dispatchBatchSize is how many due deliveries are attempted per run
+	dispatchBatchSize = 50
+	// claimLease keeps other dispatchers off a delivery while it is in flight
+	claimLease = 5 * time.Minute
+	// maxResponseBody is how much of the receiver's response is kept in the log
+	maxResponseBody = 1024
+)
+
+// Dispatcher drains the webhook outbox, retrying failed deliveries with
+// exponential backoff until MaxAttempts is reached
+type Dispatcher struct {
+	db       *database.DB
+	interval time.Duration
+
+	// HTTPClient sends the requests; replace it to point at a test receiver
+	HTTPClient  *http.Client
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDispatcher(db *database.DB, interval time.Duration, maxAttempts int) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		interval:    interval,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: maxAttempts,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+	}
+}
+
+// Start runs the dispatcher on every interval until Stop is called
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancel != nil {
+		return
+	}
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+
+	go d.loop(ctx)
+}
+
+// Stop cancels the dispatcher and waits for an in-flight run to finish
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	cancel, done := d.cancel, d.done
+	d.cancel = nil
+	d.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (d *Dispatcher) loop(ctx context.Context) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.RunOnce(ctx); err != nil {
+				log.Printf("Webhook dispatcher run failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce attempts every due delivery and returns how many were delivered
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	var due []models.WebhookDelivery
+	err := d.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(dispatchBatchSize).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		if !d.claim(&due[i]) {
+			continue
+		}
+		if d.attempt(ctx, &due[i]) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// claim pushes next_attempt_at past now, guarded on the delivery still being
+// due, so that only one dispatcher sends a given delivery
+func (d *Dispatcher) claim(delivery *models.WebhookDelivery) bool {
+	now := time.Now()
+	lease := now.Add(claimLease)
+	result := d.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.DeliveryPending, now).
+		Update("next_attempt_at", lease)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	delivery.NextAttemptAt = lease
+	return true
+}
+
+// attempt sends one delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) bool {
+	var endpoint models.WebhookEndpoint
+	if err := d.db.First(&endpoint, delivery.EndpointID).Error; err != nil {
+		d.fail(delivery, 0, "", fmt.Sprintf("endpoint not found: %v", err), true)
+		return false
+	}
+
+	status, body, err := d.send(ctx, &endpoint, delivery)
+	if err == nil && status >= 200 && status < 300 {
+		now := time.Now()
+		delivery.Attempts++
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastAttemptAt = &now
+		delivery.DeliveredAt = &now
+		delivery.ResponseStatus = status
+		delivery.ResponseBody = body
+		delivery.LastError = ""
+		d.save(delivery)
+		return true
+	}
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	} else {
+		message = "unexpected response status " + strconv.Itoa(status)
+	}
+	d.fail(delivery, status, body, message, !endpoint.Active)
+	return false
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "license-management-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now(), payload))
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(body), nil
+}
+
+// fail records a failed attempt and schedules the next one, or gives up when
+// attempts are exhausted or final is set
+func (d *Dispatcher) fail(delivery *models.WebhookDelivery, status int, body, message string, final bool) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.LastError = message
+
+	if final || delivery.Attempts >= d.MaxAttempts {
+		delivery.Status = models.DeliveryFailed
+	} else {
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+	d.save(delivery)
+}
+
+// backoff doubles the delay after each failed attempt, capped at MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return delay
+}
+
+func (d *Dispatcher) save(delivery *models.WebhookDelivery) {
+	if err := d.db.Save(delivery).Error; err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
@@ -0,0 +1,207 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cursor-ai-backend/internal/database"
+	"cursor-ai-backend/internal/database/databasetest"
+	"cursor-ai-backend/internal/models"
+)
+
+// receiver is a webhook endpoint that answers with the queued statuses in
+// turn and checks the signature of every request it gets
+type receiver struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("read body: %v", err)
+	}
+	if !Verify(r.secret, req.Header.Get(HeaderSignature), body, 5*time.Minute) {
+		r.t.Errorf("signature %q does not verify", req.Header.Get(HeaderSignature))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headers = append(r.headers, req.Header.Clone())
+	status := r.statuses[0]
+	r.statuses = r.statuses[1:]
+
+	w.WriteHeader(status)
+	io.WriteString(w, http.StatusText(status))
+}
+
+// received returns the headers of every request so far
+func (r *receiver) received() []http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.headers
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	databasetest.Drivers(t, databasetest.SQLite, func(t *testing.T, db *database.DB) {
+		receiver := &receiver{
+			t:        t,
+			secret:   "whsec_test",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusNoContent},
+		}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		endpoint := &models.WebhookEndpoint{URL: server.URL, Secret: receiver.secret, Active: true}
+		if err := db.Create(endpoint).Error; err != nil {
+			t.Fatalf("create endpoint: %v", err)
+		}
+		if err := Enqueue(db.DB, models.EventSubscriptionActivated, map[string]uint{"subscription_id": 1}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+
+		dispatcher := NewDispatcher(db, time.Minute, 5)
+		dispatcher.HTTPClient = server.Client()
+
+		// Each server error is logged and the next attempt waits twice as long
+		for attempt, backoff := range []time.Duration{dispatcher.BaseBackoff, 2 * dispatcher.BaseBackoff} {
+			// PostgreSQL keeps microseconds
+			before := time.Now().Truncate(time.Microsecond)
+			if delivered := runOnce(t, dispatcher); delivered != 0 {
+				t.Fatalf("attempt %d: %d deliveries succeeded, want 0", attempt+1, delivered)
+			}
+			after := time.Now()
+
+			delivery := onlyDelivery(t, db)
+			status := http.StatusServiceUnavailable
+			if attempt == 1 {
+				status = http.StatusInternalServerError
+			}
+			if delivery.Status != models.DeliveryPending {
+				t.Errorf("attempt %d: status %q, want %q", attempt+1, delivery.Status, models.DeliveryPending)
+			}
+			if delivery.Attempts != attempt+1 {
+				t.Errorf("attempt %d: attempts = %d", attempt+1, delivery.Attempts)
+			}
+			if delivery.ResponseStatus != status || delivery.ResponseBody != http.StatusText(status) {
+				t.Errorf("attempt %d: logged response %d %q", attempt+1, delivery.ResponseStatus, delivery.ResponseBody)
+			}
+			if delivery.LastError != "unexpected response status "+strconv.Itoa(status) {
+				t.Errorf("attempt %d: last error %q", attempt+1, delivery.LastError)
+			}
+			if delivery.LastAttemptAt == nil || delivery.DeliveredAt != nil {
+				t.Errorf("attempt %d: last attempt at %v, delivered at %v", attempt+1, delivery.LastAttemptAt, delivery.DeliveredAt)
+			}
+			if delivery.NextAttemptAt.Before(before.Add(backoff)) || delivery.NextAttemptAt.After(after.Add(backoff)) {
+				t.Errorf("attempt %d: next attempt at %v, want %v after the attempt", attempt+1, delivery.NextAttemptAt, backoff)
+			}
+
+			// Nothing is sent again until the backoff has passed
+			if delivered := runOnce(t, dispatcher); delivered != 0 || len(receiver.received()) != attempt+1 {
+				t.Fatalf("attempt %d: delivery was retried before its backoff", attempt+1)
+			}
+			makeDue(t, db, delivery)
+		}
+
+		if delivered := runOnce(t, dispatcher); delivered != 1 {
+			t.Fatalf("%d deliveries succeeded, want 1", delivered)
+		}
+		delivery := onlyDelivery(t, db)
+		if delivery.Status != models.DeliveryDelivered || delivery.DeliveredAt == nil {
+			t.Errorf("status %q, delivered at %v, want delivered", delivery.Status, delivery.DeliveredAt)
+		}
+		if delivery.Attempts != 3 || delivery.ResponseStatus != http.StatusNoContent || delivery.LastError != "" {
+			t.Errorf("logged %d attempts, response %d, error %q", delivery.Attempts, delivery.ResponseStatus, delivery.LastError)
+		}
+
+		// A delivered event is not sent again
+		makeDue(t, db, delivery)
+		if delivered := runOnce(t, dispatcher); delivered != 0 || len(receiver.received()) != 3 {
+			t.Fatalf("delivered event was sent again")
+		}
+
+		for _, header := range receiver.received() {
+			if header.Get(HeaderEvent) != models.EventSubscriptionActivated {
+				t.Errorf("event header %q", header.Get(HeaderEvent))
+			}
+			if header.Get(HeaderDelivery) != strconv.FormatUint(uint64(delivery.ID), 10) {
+				t.Errorf("delivery header %q, want %d", header.Get(HeaderDelivery), delivery.ID)
+			}
+		}
+	})
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	databasetest.Drivers(t, databasetest.SQLite, func(t *testing.T, db *database.DB) {
+		receiver := &receiver{
+			t:        t,
+			secret:   "whsec_test",
+			statuses: []int{http.StatusBadGateway, http.StatusBadGateway},
+		}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		endpoint := &models.WebhookEndpoint{URL: server.URL, Secret: receiver.secret, Active: true}
+		if err := db.Create(endpoint).Error; err != nil {
+			t.Fatalf("create endpoint: %v", err)
+		}
+		if err := Enqueue(db.DB, models.EventInvoicePaid, nil); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+
+		dispatcher := NewDispatcher(db, time.Minute, 2)
+		dispatcher.HTTPClient = server.Client()
+
+		runOnce(t, dispatcher)
+		makeDue(t, db, onlyDelivery(t, db))
+		runOnce(t, dispatcher)
+
+		delivery := onlyDelivery(t, db)
+		if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 {
+			t.Fatalf("status %q after %d attempts, want %q after 2", delivery.Status, delivery.Attempts, models.DeliveryFailed)
+		}
+		if delivery.ResponseStatus != http.StatusBadGateway {
+			t.Errorf("logged response %d", delivery.ResponseStatus)
+		}
+	})
+}
+
+func runOnce(t *testing.T, dispatcher *Dispatcher) int {
+	t.Helper()
+	delivered, err := dispatcher.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("run dispatcher: %v", err)
+	}
+	return delivered
+}
+
+func onlyDelivery(t *testing.T, db *database.DB) models.WebhookDelivery {
+	t.Helper()
+	var deliveries []models.WebhookDelivery
+	if err := db.Find(&deliveries).Error; err != nil {
+		t.Fatalf("load deliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries queued, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// makeDue moves the next attempt into the past, as if the backoff had passed
+func makeDue(t *testing.T, db *database.DB, delivery models.WebhookDelivery) {
+	t.Helper()
+	err := db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatalf("make delivery due: %v", err)
+	}
+}
@@ -1,15 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"cursor-ai-backend/docs"
+	"cursor-ai-backend/internal/auth"
 	"cursor-ai-backend/internal/config"
 	"cursor-ai-backend/internal/database"
 	"cursor-ai-backend/internal/handlers"
 	"cursor-ai-backend/internal/middleware"
 	"cursor-ai-backend/internal/models"
+	"cursor-ai-backend/internal/payments"
+	"cursor-ai-backend/internal/scheduler"
+	"cursor-ai-backend/internal/services"
+	"cursor-ai-backend/internal/webhooks"
+	"cursor-ai-backend/pkg/license"
 
 	"github.com/gin-gonic/gin"
 	"github.com/swaggo/gin-swagger"
@@ -52,36 +66,103 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if cfg.DevMode {
+		log.Println("WARNING: DEV_MODE is enabled, do not use this configuration in production")
+	}
 
 	// Initialize database
-	db, err := database.Initialize(cfg.DatabasePath)
+	db, err := database.Initialize(cfg.DatabaseDriver, cfg.DatabaseSource())
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
-	// Auto-migrate models
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Customer{},
-		&models.SubscriptionPack{},
-		&models.Subscription{},
-	)
+	migrator, err := database.NewMigrator(db)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	// Handle "migrate up|down|status" subcommands
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(migrator, os.Args[2:]))
+	}
+
+	// Refuse to run against a schema that is ahead of the code
+	if err := migrator.Check(); err != nil {
+		log.Fatal("Refusing to start: ", err)
+	}
+
+	if cfg.AutoMigrate {
+		applied, err := migrator.Up()
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+	} else {
+		pending, err := migrator.Pending()
+		if err != nil {
+			log.Fatal("Failed to check migrations:", err)
+		}
+		if len(pending) > 0 {
+			log.Fatalf("Database has %d pending migration(s); run \"migrate up\" first", len(pending))
+		}
+	}
+
+	if err := migrateLegacyAPIKeys(db); err != nil {
+		log.Fatal("Failed to migrate legacy API keys:", err)
 	}
 
 	// Create default admin user if it doesn't exist
 	createDefaultAdmin(db)
 
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(db)
+	tokens := auth.NewTokenManager(cfg)
+	invoiceService := services.NewInvoiceService(db, services.InvoiceSettings{
+		TaxRate: cfg.InvoiceTaxRate,
+		DueDays: cfg.InvoiceDueDays,
+	})
+	subscriptionService := services.NewSubscriptionService(db, invoiceService)
+	paymentProvider, fakePayments := loadPaymentProvider(cfg)
+	paymentService := services.NewPaymentService(db, subscriptionService, invoiceService, paymentProvider, cfg.CheckoutSessionTTL)
+	entitlementService := services.NewEntitlementService(db)
+	usageService := services.NewUsageService(db)
+	organizationService := services.NewOrganizationService(db, subscriptionService)
+	userHandler := handlers.NewUserHandler(db, tokens)
 	customerHandler := handlers.NewCustomerHandler(db)
 	packHandler := handlers.NewSubscriptionPackHandler(db)
-	subscriptionHandler := handlers.NewSubscriptionHandler(db)
-	sdkHandler := handlers.NewSDKHandler(db)
+	subscriptionHandler := handlers.NewSubscriptionHandler(db, subscriptionService)
+	licenseSigner, licenseVerifier := loadLicenseKeys(cfg)
+	sdkHandler := handlers.NewSDKHandler(db, subscriptionService, entitlementService, licenseSigner, licenseVerifier, cfg.SDKLoginKeyTTL)
+	seatHandler := handlers.NewSeatHandler(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	featureHandler := handlers.NewFeatureHandler(db)
+	couponHandler := handlers.NewCouponHandler(db)
+	organizationHandler := handlers.NewOrganizationHandler(db, organizationService)
+	usageHandler := handlers.NewUsageHandler(db, usageService)
+	invoiceHandler := handlers.NewInvoiceHandler(db, invoiceService)
+	paymentHandler := handlers.NewPaymentHandler(db, paymentService, fakePayments)
+
+	// Start background expiry scheduler
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	expiryScheduler := scheduler.NewExpiryScheduler(db, subscriptionService, cfg.ExpiryCheckInterval)
+	expiryScheduler.Start(ctx)
+	schedulerHandler := handlers.NewSchedulerHandler(db, expiryScheduler)
+	auditHandler := handlers.NewAuditHandler(db)
+	staffHandler := handlers.NewStaffHandler(db)
+
+	// Start webhook outbox dispatcher
+	webhookDispatcher := webhooks.NewDispatcher(db, cfg.WebhookDispatchInterval, cfg.WebhookMaxAttempts)
+	webhookDispatcher.Start(ctx)
+	webhookHandler := handlers.NewWebhookHandler(db)
 
 	// Setup router
-	router := setupRouter(db, userHandler, customerHandler, packHandler, subscriptionHandler, sdkHandler)
+	router := setupRouter(db, tokens, userHandler, customerHandler, packHandler, subscriptionHandler, sdkHandler, seatHandler, apiKeyHandler, schedulerHandler, auditHandler, staffHandler, webhookHandler, featureHandler, couponHandler, organizationHandler, usageHandler, invoiceHandler, paymentHandler)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -89,19 +170,54 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Wait for shutdown signal
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
 	}
+	expiryScheduler.Stop()
+	webhookDispatcher.Stop()
+
+	log.Println("Server stopped")
 }
 
 func setupRouter(
 	db *database.DB,
+	tokens *auth.TokenManager,
 	userHandler *handlers.UserHandler,
 	customerHandler *handlers.CustomerHandler,
 	packHandler *handlers.SubscriptionPackHandler,
 	subscriptionHandler *handlers.SubscriptionHandler,
 	sdkHandler *handlers.SDKHandler,
+	seatHandler *handlers.SeatHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
+	schedulerHandler *handlers.SchedulerHandler,
+	auditHandler *handlers.AuditHandler,
+	staffHandler *handlers.StaffHandler,
+	webhookHandler *handlers.WebhookHandler,
+	featureHandler *handlers.FeatureHandler,
+	couponHandler *handlers.CouponHandler,
+	organizationHandler *handlers.OrganizationHandler,
+	usageHandler *handlers.UsageHandler,
+	invoiceHandler *handlers.InvoiceHandler,
+	paymentHandler *handlers.PaymentHandler,
 ) *gin.Engine {
 	router := gin.Default()
 
@@ -137,38 +253,105 @@ func setupRouter(
 			auth.POST("/admin/login", userHandler.AdminLogin)
 			auth.POST("/customer/login", userHandler.CustomerLogin)
 			auth.POST("/customer/signup", userHandler.CustomerSignup)
+			auth.POST("/auth/refresh", userHandler.RefreshToken)
 		}
 
 		// Protected endpoints (JWT required)
 		v1 := api.Group("/v1")
-		v1.Use(middleware.JWTAuth())
+		v1.Use(middleware.JWTAuth(tokens, db))
 		{
+			// Session management
+			v1.POST("/auth/logout", userHandler.Logout)
+			v1.POST("/auth/logout-all", userHandler.LogoutAll)
+
 			// Admin-only endpoints
 			admin := v1.Group("/admin")
-			admin.Use(middleware.AdminOnly())
+			admin.Use(middleware.StaffOnly(), middleware.AuditLog(db))
 			{
 				// Customer management
-				admin.GET("/customers", customerHandler.ListCustomers)
-				admin.POST("/customers", customerHandler.CreateCustomer)
-				admin.GET("/customers/:id", customerHandler.GetCustomer)
-				admin.PUT("/customers/:id", customerHandler.UpdateCustomer)
-				admin.DELETE("/customers/:id", customerHandler.DeleteCustomer)
+				admin.GET("/customers", middleware.RequirePermission(models.PermissionCustomersRead), customerHandler.ListCustomers)
+				admin.POST("/customers", middleware.RequirePermission(models.PermissionCustomersWrite), customerHandler.CreateCustomer)
+				admin.GET("/customers/:id", middleware.RequirePermission(models.PermissionCustomersRead), customerHandler.GetCustomer)
+				admin.PUT("/customers/:id", middleware.RequirePermission(models.PermissionCustomersWrite), customerHandler.UpdateCustomer)
+				admin.DELETE("/customers/:id", middleware.RequirePermission(models.PermissionCustomersWrite), customerHandler.DeleteCustomer)
 
 				// Subscription pack management
-				admin.GET("/packs", packHandler.ListPacks)
-				admin.POST("/packs", packHandler.CreatePack)
-				admin.GET("/packs/:id", packHandler.GetPack)
-				admin.PUT("/packs/:id", packHandler.UpdatePack)
-				admin.DELETE("/packs/:id", packHandler.DeletePack)
+				admin.GET("/packs", middleware.RequirePermission(models.PermissionPacksRead), packHandler.ListPacks)
+				admin.POST("/packs", middleware.RequirePermission(models.PermissionPacksWrite), packHandler.CreatePack)
+				admin.GET("/packs/:id", middleware.RequirePermission(models.PermissionPacksRead), packHandler.GetPack)
+				admin.PUT("/packs/:id", middleware.RequirePermission(models.PermissionPacksWrite), packHandler.UpdatePack)
+				admin.DELETE("/packs/:id", middleware.RequirePermission(models.PermissionPacksWrite), packHandler.DeletePack)
+				admin.GET("/packs/:id/prices", middleware.RequirePermission(models.PermissionPacksRead), packHandler.ListPackPrices)
+				admin.PUT("/packs/:id/prices", middleware.RequirePermission(models.PermissionPacksWrite), packHandler.SetPackPrice)
+				admin.DELETE("/packs/:id/prices/:price_id", middleware.RequirePermission(models.PermissionPacksWrite), packHandler.RemovePackPrice)
+				admin.GET("/packs/:id/features", middleware.RequirePermission(models.PermissionPacksRead), featureHandler.ListPackFeatures)
+				admin.PUT("/packs/:id/features/:feature_id", middleware.RequirePermission(models.PermissionPacksWrite), featureHandler.SetPackFeature)
+				admin.DELETE("/packs/:id/features/:feature_id", middleware.RequirePermission(models.PermissionPacksWrite), featureHandler.RemovePackFeature)
+
+				// Feature catalog
+				admin.GET("/features", middleware.RequirePermission(models.PermissionFeaturesRead), featureHandler.ListFeatures)
+				admin.POST("/features", middleware.RequirePermission(models.PermissionFeaturesWrite), featureHandler.CreateFeature)
+				admin.GET("/features/:id", middleware.RequirePermission(models.PermissionFeaturesRead), featureHandler.GetFeature)
+				admin.PUT("/features/:id", middleware.RequirePermission(models.PermissionFeaturesWrite), featureHandler.UpdateFeature)
+				admin.DELETE("/features/:id", middleware.RequirePermission(models.PermissionFeaturesWrite), featureHandler.DeleteFeature)
+
+				// Coupons
+				admin.GET("/coupons", middleware.RequirePermission(models.PermissionCouponsRead), couponHandler.ListCoupons)
+				admin.POST("/coupons", middleware.RequirePermission(models.PermissionCouponsWrite), couponHandler.CreateCoupon)
+				admin.GET("/coupons/:id", middleware.RequirePermission(models.PermissionCouponsRead), couponHandler.GetCoupon)
+				admin.PUT("/coupons/:id", middleware.RequirePermission(models.PermissionCouponsWrite), couponHandler.UpdateCoupon)
+				admin.DELETE("/coupons/:id", middleware.RequirePermission(models.PermissionCouponsWrite), couponHandler.DeleteCoupon)
+				admin.GET("/coupons/:id/redemptions", middleware.RequirePermission(models.PermissionCouponsRead), couponHandler.ListCouponRedemptions)
+
+				// Organizations
+				admin.GET("/organizations", middleware.RequirePermission(models.PermissionOrganizationsRead), organizationHandler.ListOrganizations)
+				admin.GET("/organizations/:id", middleware.RequirePermission(models.PermissionOrganizationsRead), organizationHandler.GetOrganizationAdmin)
+
+				// Usage reports
+				admin.GET("/usage", middleware.RequirePermission(models.PermissionUsageRead), usageHandler.ListUsage)
+				admin.GET("/usage/summary", middleware.RequirePermission(models.PermissionUsageRead), usageHandler.GetUsageSummary)
+
+				// Invoices
+				admin.GET("/invoices", middleware.RequirePermission(models.PermissionInvoicesRead), invoiceHandler.ListInvoices)
+				admin.GET("/invoices/:id", middleware.RequirePermission(models.PermissionInvoicesRead), invoiceHandler.GetInvoice)
+				admin.GET("/invoices/:id/document", middleware.RequirePermission(models.PermissionInvoicesRead), invoiceHandler.GetInvoiceDocument)
+				admin.PUT("/invoices/:id/pay", middleware.RequirePermission(models.PermissionInvoicesWrite), invoiceHandler.MarkInvoicePaid)
+				admin.PUT("/invoices/:id/void", middleware.RequirePermission(models.PermissionInvoicesWrite), invoiceHandler.VoidInvoice)
+				admin.GET("/payments", middleware.RequirePermission(models.PermissionInvoicesRead), paymentHandler.ListPayments)
 
 				// Subscription management
-				admin.GET("/subscriptions", subscriptionHandler.ListSubscriptions)
-				admin.POST("/subscriptions", subscriptionHandler.CreateSubscription)
-				admin.GET("/subscriptions/:id", subscriptionHandler.GetSubscription)
-				admin.PUT("/subscriptions/:id/approve", subscriptionHandler.ApproveSubscription)
-				admin.PUT("/subscriptions/:id/assign", subscriptionHandler.AssignSubscription)
-				admin.PUT("/subscriptions/:id/unassign", subscriptionHandler.UnassignSubscription)
-				admin.DELETE("/subscriptions/:id", subscriptionHandler.DeleteSubscription)
+				admin.GET("/subscriptions", middleware.RequirePermission(models.PermissionSubscriptionsRead), subscriptionHandler.ListSubscriptions)
+				admin.POST("/subscriptions", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.CreateSubscription)
+				admin.GET("/subscriptions/:id", middleware.RequirePermission(models.PermissionSubscriptionsRead), subscriptionHandler.GetSubscription)
+				admin.GET("/subscriptions/:id/events", middleware.RequirePermission(models.PermissionSubscriptionsRead), subscriptionHandler.GetSubscriptionEvents)
+				admin.PUT("/subscriptions/:id/approve", middleware.RequirePermission(models.PermissionSubscriptionsApprove), subscriptionHandler.ApproveSubscription)
+				admin.PUT("/subscriptions/:id/assign", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.AssignSubscription)
+				admin.PUT("/subscriptions/:id/unassign", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.UnassignSubscription)
+				admin.PUT("/subscriptions/:id/suspend", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.SuspendSubscription)
+				admin.PUT("/subscriptions/:id/resume", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.ResumeSubscription)
+				admin.POST("/subscriptions/:id/change-plan", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.ChangeSubscriptionPlan)
+				admin.DELETE("/subscriptions/:id", middleware.RequirePermission(models.PermissionSubscriptionsWrite), subscriptionHandler.DeleteSubscription)
+
+				// System status
+				admin.GET("/scheduler/expiry", middleware.RequirePermission(models.PermissionSystemRead), schedulerHandler.GetExpiryStatus)
+				admin.GET("/audit", middleware.RequirePermission(models.PermissionAuditRead), auditHandler.ListAuditLogs)
+				admin.GET("/audit/export", middleware.RequirePermission(models.PermissionAuditRead), auditHandler.ExportAuditLogs)
+
+				// Staff and roles
+				admin.GET("/roles", middleware.RequirePermission(models.PermissionStaffRead), staffHandler.ListRoles)
+				admin.GET("/staff", middleware.RequirePermission(models.PermissionStaffRead), staffHandler.ListStaff)
+				admin.POST("/staff", middleware.RequirePermission(models.PermissionStaffWrite), staffHandler.CreateStaff)
+				admin.GET("/staff/:id", middleware.RequirePermission(models.PermissionStaffRead), staffHandler.GetStaff)
+				admin.PUT("/staff/:id", middleware.RequirePermission(models.PermissionStaffWrite), staffHandler.UpdateStaff)
+				admin.DELETE("/staff/:id", middleware.RequirePermission(models.PermissionStaffWrite), staffHandler.DeleteStaff)
+
+				admin.GET("/webhooks", middleware.RequirePermission(models.PermissionWebhooksRead), webhookHandler.ListWebhooks)
+				admin.POST("/webhooks", middleware.RequirePermission(models.PermissionWebhooksWrite), webhookHandler.CreateWebhook)
+				admin.GET("/webhooks/:id", middleware.RequirePermission(models.PermissionWebhooksRead), webhookHandler.GetWebhook)
+				admin.PUT("/webhooks/:id", middleware.RequirePermission(models.PermissionWebhooksWrite), webhookHandler.UpdateWebhook)
+				admin.DELETE("/webhooks/:id", middleware.RequirePermission(models.PermissionWebhooksWrite), webhookHandler.DeleteWebhook)
+				admin.GET("/webhooks/:id/deliveries", middleware.RequirePermission(models.PermissionWebhooksRead), webhookHandler.ListDeliveries)
+				admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", middleware.RequirePermission(models.PermissionWebhooksWrite), webhookHandler.RedeliverDelivery)
 			}
 
 			// Customer endpoints
@@ -177,28 +360,85 @@ func setupRouter(
 			{
 				customer.GET("/profile", customerHandler.GetProfile)
 				customer.PUT("/profile", customerHandler.UpdateProfile)
+				customer.GET("/packs", packHandler.ListOffers)
 				customer.GET("/subscription", subscriptionHandler.GetCurrentSubscription)
 				customer.POST("/subscription/request", subscriptionHandler.RequestSubscription)
+				customer.POST("/subscription/trial", subscriptionHandler.StartTrial)
+				customer.POST("/subscription/checkout", paymentHandler.CreateCheckout)
 				customer.PUT("/subscription/deactivate", subscriptionHandler.DeactivateSubscription)
+				customer.POST("/subscription/renew", subscriptionHandler.RenewSubscription)
+				customer.PUT("/subscription/auto-renew", subscriptionHandler.SetAutoRenew)
+				customer.POST("/subscription/change-plan", subscriptionHandler.ChangePlan)
 				customer.GET("/subscription/history", subscriptionHandler.GetSubscriptionHistory)
+				customer.GET("/invoices", invoiceHandler.ListMyInvoices)
+				customer.GET("/invoices/:id", invoiceHandler.GetMyInvoice)
+				customer.GET("/invoices/:id/document", invoiceHandler.GetMyInvoiceDocument)
+
+				// API key management
+				customer.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+				customer.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+				customer.POST("/api-keys/:id/rotate", apiKeyHandler.RotateAPIKey)
+				customer.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+
+				// Organizations
+				customer.GET("/organizations", organizationHandler.ListMyOrganizations)
+				customer.POST("/organizations", organizationHandler.CreateOrganization)
+				customer.GET("/organizations/:id", organizationHandler.GetOrganization)
+				customer.PUT("/organizations/:id", organizationHandler.UpdateOrganization)
+				customer.DELETE("/organizations/:id", organizationHandler.DeleteOrganization)
+				customer.PUT("/organizations/:id/members/:customer_id", organizationHandler.SetMemberRole)
+				customer.DELETE("/organizations/:id/members/:customer_id", organizationHandler.RemoveMember)
+				customer.GET("/organizations/:id/invitations", organizationHandler.ListInvitations)
+				customer.POST("/organizations/:id/invitations", organizationHandler.InviteMember)
+				customer.DELETE("/organizations/:id/invitations/:invitation_id", organizationHandler.RevokeInvitation)
+				customer.GET("/organizations/:id/subscription", organizationHandler.GetOrganizationSubscription)
+				customer.POST("/organizations/:id/subscription/request", organizationHandler.RequestOrganizationSubscription)
+				customer.POST("/invitations/accept", organizationHandler.AcceptInvitation)
 			}
 		}
 	}
 
+	// Payment provider callbacks (authenticated by the provider's signature)
+	paymentRoutes := router.Group("/payments")
+	{
+		paymentRoutes.POST("/webhooks/:provider", paymentHandler.HandleWebhook)
+		if paymentHandler.FakeEnabled() {
+			paymentRoutes.GET("/fake/checkout/:session_id", paymentHandler.FakeCheckout)
+			paymentRoutes.POST("/fake/checkout/:session_id/:result", paymentHandler.CompleteFakeCheckout)
+		}
+	}
+
 	// SDK API routes (API Key authentication)
 	sdk := router.Group("/sdk")
 	{
 		// Public SDK authentication
 		sdk.POST("/auth/login", sdkHandler.Login)
+		sdk.GET("/license/keys", sdkHandler.GetLicenseKeys)
 
 		// Protected SDK endpoints (API Key required)
 		sdkV1 := sdk.Group("/v1")
 		sdkV1.Use(middleware.APIKeyAuth())
 		{
-			sdkV1.GET("/subscription", sdkHandler.GetCurrentSubscription)
-			sdkV1.POST("/subscription/request", sdkHandler.RequestSubscription)
-			sdkV1.PUT("/subscription/deactivate", sdkHandler.DeactivateSubscription)
-			sdkV1.GET("/subscription/history", sdkHandler.GetSubscriptionHistory)
+			sdkV1.GET("/packs", middleware.RequireScope(models.ScopeSubscriptionRead), packHandler.ListOffers)
+			sdkV1.GET("/subscription", middleware.RequireScope(models.ScopeSubscriptionRead), sdkHandler.GetCurrentSubscription)
+			sdkV1.POST("/subscription/request", middleware.RequireScope(models.ScopeSubscriptionRequest), sdkHandler.RequestSubscription)
+			sdkV1.POST("/subscription/trial", middleware.RequireScope(models.ScopeSubscriptionRequest), sdkHandler.StartTrial)
+			sdkV1.PUT("/subscription/deactivate", middleware.RequireScope(models.ScopeSubscriptionDeactivate), sdkHandler.DeactivateSubscription)
+			sdkV1.POST("/subscription/renew", middleware.RequireScope(models.ScopeSubscriptionRequest), sdkHandler.RenewSubscription)
+			sdkV1.PUT("/subscription/auto-renew", middleware.RequireScope(models.ScopeSubscriptionRequest), sdkHandler.SetAutoRenew)
+			sdkV1.POST("/subscription/change-plan", middleware.RequireScope(models.ScopeSubscriptionRequest), sdkHandler.ChangePlan)
+			sdkV1.GET("/subscription/history", middleware.RequireScope(models.ScopeSubscriptionRead), sdkHandler.GetSubscriptionHistory)
+			sdkV1.GET("/license", middleware.RequireScope(models.ScopeLicenseRead), sdkHandler.GetLicense)
+			sdkV1.GET("/entitlements", middleware.RequireScope(models.ScopeSubscriptionRead), sdkHandler.GetEntitlements)
+
+			// Metered usage
+			sdkV1.POST("/usage", middleware.RequireScope(models.ScopeUsageWrite), usageHandler.ReportUsage)
+			sdkV1.GET("/quota", middleware.RequireScope(models.ScopeSubscriptionRead), usageHandler.GetQuota)
+
+			// Machine seats
+			sdkV1.GET("/seats", middleware.RequireScope(models.ScopeSeatRead), seatHandler.ListSeats)
+			sdkV1.POST("/seats/activate", middleware.RequireScope(models.ScopeSeatWrite), seatHandler.ActivateSeat)
+			sdkV1.POST("/seats/deactivate", middleware.RequireScope(models.ScopeSeatWrite), seatHandler.DeactivateSeat)
 		}
 	}
 
@@ -207,13 +447,13 @@ func setupRouter(
 
 func createDefaultAdmin(db *database.DB) {
 	var count int64
-	db.Model(&models.User{}).Where("role = ?", "admin").Count(&count)
+	db.Model(&models.User{}).Where("role = ?", models.RoleSuperAdmin).Count(&count)
 	
 	if count == 0 {
 		admin := &models.User{
 			Email:    "admin@example.com",
 			Password: "admin123", // In production, this should be hashed
-			Role:     "admin",
+			Role:     models.RoleSuperAdmin,
 		}
 		
 		if err := admin.HashPassword(); err != nil {
@@ -228,3 +468,151 @@ func createDefaultAdmin(db *database.DB) {
 		}
 	}
 }
+
+// runMigrateCommand handles "migrate up", "migrate down [steps]" and "migrate status"
+func runMigrateCommand(migrator *database.Migrator, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up | down [steps] | status")
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate up failed:", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "steps must be a positive integer")
+				return 2
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate down failed:", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate status failed:", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if !status.Known {
+				state += " (unknown to this build)"
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n", args[0])
+		return 2
+	}
+
+	return 0
+}
+
+// migrateLegacyAPIKeys moves plaintext keys from the old users.api_key column
+// into the api_keys table as hashes and clears the plaintext copy
+func migrateLegacyAPIKeys(db *database.DB) error {
+	if !db.Migrator().HasColumn("users", "api_key") {
+		return nil
+	}
+
+	var legacy []struct {
+		ID     uint
+		APIKey string
+	}
+	err := db.Table("users").Select("id, api_key").Where("api_key IS NOT NULL AND api_key <> ''").Scan(&legacy).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range legacy {
+		key := &models.APIKey{
+			UserID:  row.ID,
+			Name:    "Legacy SDK key",
+			Prefix:  row.APIKey[:min(len(row.APIKey), models.APIKeyPrefixLength)],
+			KeyHash: models.HashAPIKey(row.APIKey),
+			Scopes:  models.AllAPIKeyScopes,
+		}
+		if err := db.Create(key).Error; err != nil {
+			return err
+		}
+		if err := db.Table("users").Where("id = ?", row.ID).Update("api_key", nil).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(legacy) > 0 {
+		log.Printf("Migrated %d legacy API key(s)", len(legacy))
+	}
+	return nil
+}
+
+// loadPaymentProvider returns the configured payment provider, if any, and the
+// fake provider when that is the one in use
+func loadPaymentProvider(cfg *config.Config) (payments.Provider, *payments.FakeProvider) {
+	switch cfg.PaymentProvider {
+	case "fake":
+		secret := cfg.PaymentWebhookSecret
+		if secret == "" {
+			var err error
+			if secret, err = webhooks.GenerateSecret(); err != nil {
+				log.Fatal("Failed to generate payment webhook secret:", err)
+			}
+		}
+		log.Println("WARNING: using the fake payment provider, no real payments are taken")
+		fake := payments.NewFakeProvider(secret, cfg.PublicURL)
+		return fake, fake
+	default:
+		log.Println("PAYMENT_PROVIDER not set, online checkout is disabled")
+		return nil, nil
+	}
+}
+
+// loadLicenseKeys builds the license signer and the keyring of trusted public keys
+func loadLicenseKeys(cfg *config.Config) (*license.Signer, *license.Verifier) {
+	trusted, err := license.ParseKeyring(cfg.LicenseTrustedKeys)
+	if err != nil {
+		log.Fatal("Failed to parse LICENSE_TRUSTED_KEYS:", err)
+	}
+	verifier := license.NewVerifier(trusted)
+
+	if cfg.LicenseSigningKey == "" {
+		log.Println("LICENSE_SIGNING_KEY not set, signed license files are disabled")
+		return nil, verifier
+	}
+
+	key, err := license.ParsePrivateKey(cfg.LicenseSigningKey)
+	if err != nil {
+		log.Fatal("Failed to parse LICENSE_SIGNING_KEY:", err)
+	}
+
+	signer, err := license.NewSigner(cfg.LicenseKeyID, key)
+	if err != nil {
+		log.Fatal("Failed to create license signer:", err)
+	}
+	verifier.AddKey(signer.KeyID(), signer.PublicKey())
+
+	return signer, verifier
+}
@@ -0,0 +1,240 @@
+// Package license issues and verifies Ed25519-signed license files.
+//
+// The verification half of this package has no dependencies outside the
+// standard library so that desktop and mobile clients can embed it and
+// check a license file offline against a keyring of trusted public keys.
+package license
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Algorithm is the signature algorithm used for license files
+const Algorithm = "Ed25519"
+
+var (
+	ErrMalformed        = errors.New("license: malformed license file")
+	ErrUnknownKey       = errors.New("license: unknown key id")
+	ErrInvalidSignature = errors.New("license: invalid signature")
+	ErrExpired          = errors.New("license: license has expired")
+)
+
+// Claims is the signed content of a license file
+type Claims struct {
+	SubscriptionID uint      `json:"subscription_id"`
+	CustomerID     uint      `json:"customer_id"`
+	CustomerEmail  string    `json:"customer_email,omitempty"`
+	PackSKU        string    `json:"pack_sku"`
+	IssuedAt       time.Time `json:"issued_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	KeyID          string    `json:"kid"`
+
+	// GraceEndsAt extends access past ExpiresAt; clients should warn the user
+	// while InGrace reports true
+	GraceEndsAt *time.Time `json:"grace_ends_at,omitempty"`
+
+	// OrganizationID is set when the customer is licensed as a member of an
+	// organization that owns the subscription
+	OrganizationID *uint `json:"organization_id,omitempty"`
+}
+
+// InGrace reports whether now falls in the grace period after ExpiresAt
+func (c *Claims) InGrace(now time.Time) bool {
+	return !now.Before(c.ExpiresAt) && c.GraceEndsAt != nil && now.Before(*c.GraceEndsAt)
+}
+
+// File is the envelope handed to clients. Payload is the base64url encoded
+// JSON of Claims and Signature is the Ed25519 signature over those bytes.
+type File struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// Signer signs license claims with a single private key
+type Signer struct {
+	keyID string
+	key   ed25519.PrivateKey
+}
+
+func NewSigner(keyID string, key ed25519.PrivateKey) (*Signer, error) {
+	if keyID == "" {
+		return nil, errors.New("license: key id is required")
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("license: invalid private key size")
+	}
+	return &Signer{keyID: keyID, key: key}, nil
+}
+
+// KeyID returns the id of the signing key
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the public half of the signing key
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign stamps the claims with the signer's key id and returns a signed file
+func (s *Signer) Sign(claims Claims) (*File, error) {
+	claims.KeyID = s.keyID
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Algorithm: Algorithm,
+		KeyID:     s.keyID,
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+		Signature: base64.RawURLEncoding.EncodeToString(ed25519.Sign(s.key, payload)),
+	}, nil
+}
+
+// Verifier checks license files against a keyring of trusted public keys.
+// Keep retired keys in the keyring so files signed before a rotation stay valid.
+type Verifier struct {
+	keys map[string]ed25519.PublicKey
+}
+
+func NewVerifier(keys map[string]ed25519.PublicKey) *Verifier {
+	v := &Verifier{keys: make(map[string]ed25519.PublicKey, len(keys))}
+	for keyID, key := range keys {
+		v.AddKey(keyID, key)
+	}
+	return v
+}
+
+// AddKey trusts an additional public key
+func (v *Verifier) AddKey(keyID string, key ed25519.PublicKey) {
+	v.keys[keyID] = key
+}
+
+// Keys returns a copy of the trusted keyring
+func (v *Verifier) Keys() map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey, len(v.keys))
+	for keyID, key := range v.keys {
+		keys[keyID] = key
+	}
+	return keys
+}
+
+// Verify checks the signature of a license file and that it has not expired at
+// now, counting any grace period
+func (v *Verifier) Verify(file *File, now time.Time) (*Claims, error) {
+	if file == nil || file.Algorithm != Algorithm || file.Payload == "" || file.Signature == "" {
+		return nil, ErrMalformed
+	}
+
+	key, ok := v.keys[file.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(file.Payload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(file.Signature)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if !ed25519.Verify(key, payload, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	// The envelope key id is not signed, so it must agree with the signed one
+	if claims.KeyID != file.KeyID {
+		return nil, ErrInvalidSignature
+	}
+	if !now.Before(claims.ExpiresAt) && !claims.InGrace(now) {
+		return &claims, ErrExpired
+	}
+
+	return &claims, nil
+}
+
+// VerifyBytes parses a JSON encoded license file and verifies it
+func (v *Verifier) VerifyBytes(data []byte, now time.Time) (*Claims, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, ErrMalformed
+	}
+	return v.Verify(&file, now)
+}
+
+// GenerateKey creates a new base64 encoded Ed25519 private key seed
+func GenerateKey() (string, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(seed), nil
+}
+
+// ParsePrivateKey decodes a base64 encoded Ed25519 seed or full private key
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("license: decode private key: %w", err)
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, errors.New("license: invalid private key size")
+	}
+}
+
+// ParsePublicKey decodes a base64 encoded Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("license: decode public key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("license: invalid public key size")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ParseKeyring decodes a comma separated list of kid=base64-public-key pairs
+func ParseKeyring(encoded string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, entry := range strings.Split(encoded, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyID, value, ok := strings.Cut(entry, "=")
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("license: invalid keyring entry %q", entry)
+		}
+
+		key, err := ParsePublicKey(value)
+		if err != nil {
+			return nil, err
+		}
+		keys[keyID] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,254 @@
+package license_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"cursor-ai-backend/pkg/license"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newSigner(t *testing.T, keyID string) *license.Signer {
+	t.Helper()
+	encoded, err := license.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	key, err := license.ParsePrivateKey(encoded)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	signer, err := license.NewSigner(keyID, key)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	return signer
+}
+
+func sign(t *testing.T, signer *license.Signer, claims license.Claims) *license.File {
+	t.Helper()
+	file, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return file
+}
+
+func validClaims() license.Claims {
+	return license.Claims{
+		SubscriptionID: 7,
+		CustomerID:     3,
+		CustomerEmail:  "customer@example.com",
+		PackSKU:        "PRO",
+		IssuedAt:       now.Add(-24 * time.Hour),
+		ExpiresAt:      now.Add(30 * 24 * time.Hour),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := newSigner(t, "2026-01")
+	verifier := license.NewVerifier(map[string]ed25519.PublicKey{signer.KeyID(): signer.PublicKey()})
+
+	data, err := json.Marshal(sign(t, signer, validClaims()))
+	if err != nil {
+		t.Fatalf("encode file: %v", err)
+	}
+	claims, err := verifier.VerifyBytes(data, now)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	want := validClaims()
+	if claims.SubscriptionID != want.SubscriptionID || claims.CustomerID != want.CustomerID ||
+		claims.CustomerEmail != want.CustomerEmail || claims.PackSKU != want.PackSKU ||
+		!claims.ExpiresAt.Equal(want.ExpiresAt) || !claims.IssuedAt.Equal(want.IssuedAt) {
+		t.Errorf("claims = %+v, want %+v", claims, want)
+	}
+	if claims.KeyID != signer.KeyID() {
+		t.Errorf("kid = %q, want %q", claims.KeyID, signer.KeyID())
+	}
+	if claims.InGrace(now) {
+		t.Errorf("license is in grace before it expired")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	signer := newSigner(t, "current")
+	other := newSigner(t, "other")
+
+	tests := []struct {
+		name   string
+		keys   map[string]ed25519.PublicKey
+		tamper func(file *license.File)
+		want   error
+	}{
+		{
+			name: "tampered payload",
+			tamper: func(file *license.File) {
+				claims := validClaims()
+				claims.KeyID = signer.KeyID()
+				claims.PackSKU = "ENTERPRISE"
+				payload, _ := json.Marshal(claims)
+				file.Payload = base64.RawURLEncoding.EncodeToString(payload)
+			},
+			want: license.ErrInvalidSignature,
+		},
+		{
+			name: "tampered signature",
+			tamper: func(file *license.File) {
+				signature, _ := base64.RawURLEncoding.DecodeString(file.Signature)
+				signature[0] ^= 0xff
+				file.Signature = base64.RawURLEncoding.EncodeToString(signature)
+			},
+			want: license.ErrInvalidSignature,
+		},
+		{
+			name: "signature by another key",
+			tamper: func(file *license.File) {
+				forged := sign(t, other, validClaims())
+				file.Signature = forged.Signature
+			},
+			want: license.ErrInvalidSignature,
+		},
+		{
+			name: "unknown kid",
+			tamper: func(file *license.File) {
+				file.KeyID = "retired"
+			},
+			want: license.ErrUnknownKey,
+		},
+		{
+			// The same public key trusted under a second kid: the signature
+			// verifies, but the signed kid disagrees with the envelope
+			name: "envelope kid does not match claims",
+			keys: map[string]ed25519.PublicKey{"alias": signer.PublicKey()},
+			tamper: func(file *license.File) {
+				file.KeyID = "alias"
+			},
+			want: license.ErrInvalidSignature,
+		},
+		{
+			name: "malformed payload",
+			tamper: func(file *license.File) {
+				file.Payload = "not base64!"
+			},
+			want: license.ErrMalformed,
+		},
+		{
+			name: "unknown algorithm",
+			tamper: func(file *license.File) {
+				file.Algorithm = "HS256"
+			},
+			want: license.ErrMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := license.NewVerifier(map[string]ed25519.PublicKey{signer.KeyID(): signer.PublicKey()})
+			for keyID, key := range tt.keys {
+				verifier.AddKey(keyID, key)
+			}
+
+			file := sign(t, signer, validClaims())
+			tt.tamper(file)
+			if _, err := verifier.Verify(file, now); !errors.Is(err, tt.want) {
+				t.Fatalf("verify: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyExpiryAndGrace(t *testing.T) {
+	signer := newSigner(t, "current")
+	verifier := license.NewVerifier(map[string]ed25519.PublicKey{signer.KeyID(): signer.PublicKey()})
+
+	expiresAt := now
+	graceEndsAt := now.Add(7 * 24 * time.Hour)
+	claims := validClaims()
+	claims.ExpiresAt = expiresAt
+	withoutGrace := sign(t, signer, claims)
+	claims.GraceEndsAt = &graceEndsAt
+	withGrace := sign(t, signer, claims)
+
+	tests := []struct {
+		name    string
+		file    *license.File
+		at      time.Time
+		want    error
+		inGrace bool
+	}{
+		{name: "before expiry", file: withoutGrace, at: expiresAt.Add(-time.Second)},
+		{name: "at expiry", file: withoutGrace, at: expiresAt, want: license.ErrExpired},
+		{name: "in grace", file: withGrace, at: expiresAt.Add(24 * time.Hour), inGrace: true},
+		{name: "at grace end", file: withGrace, at: graceEndsAt, want: license.ErrExpired},
+		{name: "grace not started", file: withGrace, at: expiresAt.Add(-time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.file, tt.at)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("verify: got %v, want %v", err, tt.want)
+			}
+			if claims == nil {
+				t.Fatalf("verify returned no claims")
+			}
+			if claims.InGrace(tt.at) != tt.inGrace {
+				t.Errorf("in grace = %t, want %t", claims.InGrace(tt.at), tt.inGrace)
+			}
+		})
+	}
+}
+
+// Rotating the signing key must not break license files already issued, as
+// long as the retired public key stays in the keyring
+func TestVerifyAfterKeyRotation(t *testing.T) {
+	retired := newSigner(t, "2025-01")
+	issued := sign(t, retired, validClaims())
+
+	current := newSigner(t, "2026-01")
+	keyring, err := license.ParseKeyring("2025-01=" + base64.StdEncoding.EncodeToString(retired.PublicKey()))
+	if err != nil {
+		t.Fatalf("parse keyring: %v", err)
+	}
+	verifier := license.NewVerifier(keyring)
+	verifier.AddKey(current.KeyID(), current.PublicKey())
+
+	if claims, err := verifier.Verify(issued, now); err != nil || claims.KeyID != retired.KeyID() {
+		t.Fatalf("file signed before rotation: claims %+v, error %v", claims, err)
+	}
+	if claims, err := verifier.Verify(sign(t, current, validClaims()), now); err != nil || claims.KeyID != current.KeyID() {
+		t.Fatalf("file signed after rotation: claims %+v, error %v", claims, err)
+	}
+
+	// Dropping the retired key from the keyring invalidates its files
+	withoutRetired := license.NewVerifier(map[string]ed25519.PublicKey{current.KeyID(): current.PublicKey()})
+	if _, err := withoutRetired.Verify(issued, now); !errors.Is(err, license.ErrUnknownKey) {
+		t.Fatalf("file signed by a dropped key: got %v, want %v", err, license.ErrUnknownKey)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	signer := newSigner(t, "a")
+	encoded := base64.StdEncoding.EncodeToString(signer.PublicKey())
+
+	keys, err := license.ParseKeyring(" a=" + encoded + ", ,b=" + encoded)
+	if err != nil {
+		t.Fatalf("parse keyring: %v", err)
+	}
+	if len(keys) != 2 || !keys["a"].Equal(signer.PublicKey()) || !keys["b"].Equal(signer.PublicKey()) {
+		t.Errorf("keyring = %v", keys)
+	}
+
+	for _, invalid := range []string{"a", "=" + encoded, "a=not-base64", "a=" + base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := license.ParseKeyring(invalid); err == nil {
+			t.Errorf("keyring %q was accepted", invalid)
+		}
+	}
+}